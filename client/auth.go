@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// TokenSource supplies bearer tokens, refreshing them as needed; Token is
+// called for every request, so it should cache.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc adapts a function to TokenSource.
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+func (fn TokenSourceFunc) Token(ctx context.Context) (string, error) { return fn(ctx) }
+
+// SetBearerToken sends "Authorization: Bearer <token>" on every request,
+// replacing any previously set authentication.
+func (c *Client) SetBearerToken(token string) {
+	c.setAuth(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// SetBasicAuth sends HTTP Basic credentials on every request, replacing
+// any previously set authentication.
+func (c *Client) SetBasicAuth(user, password string) {
+	c.setAuth(func(req *http.Request) error {
+		req.SetBasicAuth(user, password)
+		return nil
+	})
+}
+
+// SetTokenSource sends a bearer token from ts on every request, replacing
+// any previously set authentication. A failing ts fails the request.
+func (c *Client) SetTokenSource(ts TokenSource) {
+	c.setAuth(func(req *http.Request) error {
+		token, err := ts.Token(req.Context())
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// setAuth installs the auth interceptor on first use and swaps in authorize.
+func (c *Client) setAuth(authorize func(req *http.Request) error) {
+	if c.auth.Swap(&authorize) == nil {
+		c.Use(c.authenticate)
+	}
+}
+
+// authenticate is the interceptor adding credentials to requests that
+// don't carry an Authorization header already. Like net/http, it doesn't
+// follow redirects to other hosts with them.
+func (c *Client) authenticate(next RoundTripFunc) RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		authorize := c.auth.Load()
+		if authorize == nil || req.Header.Get("Authorization") != "" || !sameHostAsOrigin(req) {
+			return next(req)
+		}
+		req = req.Clone(req.Context())
+		if err := (*authorize)(req); err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+		return next(req)
+	}
+}
+
+// sameHostAsOrigin reports whether req, possibly a redirect, goes to the
+// host the first request of its chain went to.
+func sameHostAsOrigin(req *http.Request) bool {
+	origin := req
+	for origin.Response != nil && origin.Response.Request != nil {
+		origin = origin.Response.Request
+	}
+	return origin.URL.Host == req.URL.Host
+}
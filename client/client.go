@@ -1,8 +1,12 @@
 package client
 
 import (
+	"context"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -11,57 +15,175 @@ import (
 type Client struct {
 	*http.Client
 	Timeout time.Duration
+
+	baseURL         string
+	header          http.Header
+	query           url.Values
+	disallowUnknown bool
+	retry           *RetryConfig
+	noCache         bool
+	maxResponse     int64
+	acceptEncoding  string
+	decoders        map[string]Decoder
+	auth            atomic.Pointer[func(req *http.Request) error]
+}
+
+// Option configures a Client in NewClient.
+type Option func(c *Client)
+
+// WithBaseURL resolves relative request URLs ("/v1/users") against base,
+// e.g. "https://api.example.com". A path in base is kept as a prefix;
+// absolute URLs are used as is.
+func WithBaseURL(base string) Option {
+	return func(c *Client) { c.baseURL = strings.TrimSuffix(base, "/") }
+}
+
+// WithHeader sends the header on every request unless the request sets it.
+func WithHeader(key, value string) Option {
+	return func(c *Client) { c.header.Set(key, value) }
+}
+
+// WithUserAgent sets the default User-Agent header.
+func WithUserAgent(ua string) Option {
+	return WithHeader("User-Agent", ua)
+}
+
+// WithQueryParam adds the query parameter to every request unless the
+// request or its URL sets it, e.g. an API key or version.
+func WithQueryParam(key, value string) Option {
+	return func(c *Client) { c.query.Set(key, value) }
+}
+
+// WithDisallowUnknownFields makes Response.JSONInto reject objects with
+// fields the target struct lacks, to catch API drift early.
+func WithDisallowUnknownFields() Option {
+	return func(c *Client) { c.disallowUnknown = true }
+}
+
+// WithoutBodyCache stops responses from keeping their body after Bytes,
+// String or a JSON method reads it, so large bodies can be freed; a
+// second read then fails. See also Response.Reader.
+func WithoutBodyCache() Option {
+	return func(c *Client) { c.noCache = true }
 }
 
 // NewClient creates a new HTTP client with an optional timeout.
 // If timeout == 0, it uses the default http.Client timeout behavior.
-func NewClient(timeout time.Duration) *Client {
+func NewClient(timeout time.Duration, opts ...Option) *Client {
 	httpClient := &http.Client{}
 	if timeout > 0 {
 		httpClient.Timeout = timeout
 	}
-	return &Client{
+	c := &Client{
 		Client:  httpClient,
 		Timeout: timeout,
+		header:  http.Header{},
+		query:   url.Values{},
 	}
-}
-
-// executeRequest creates and sends an HTTP request, returning a Response wrapper.
-func (c *Client) executeRequest(method, baseURL string, params, headers map[string]string, body io.Reader) (*Response, error) {
-	fullURL, err := buildURL(baseURL, params)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest(method, fullURL, body)
-	if err != nil {
-		return nil, err
-	}
-
-	for k, v := range headers {
-		req.Header.Set(k, v)
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
+}
 
-	resp, err := c.Do(req)
-	if err != nil {
-		return nil, err
-	}
+// Send sends a request with any method, optional query parameters,
+// headers and body. contentType, if set, overrides the Content-Type header.
+// (Do remains http.Client's, taking an *http.Request.)
+func (c *Client) Send(method, baseURL string, params, headers map[string]string, payload io.Reader, contentType string) (*Response, error) {
+	return c.SendCtx(context.Background(), method, baseURL, params, headers, payload, contentType)
+}
 
-	return &Response{Response: resp}, nil
+// SendCtx is Send bound to ctx: canceling ctx aborts the request, e.g.
+// when passed a server request's context.
+func (c *Client) SendCtx(ctx context.Context, method, baseURL string, params, headers map[string]string, payload io.Reader, contentType string) (*Response, error) {
+	return c.R().SetContext(ctx).SetQueryParams(params).SetHeaders(headers).SetBody(payload, contentType).Send(method, baseURL)
 }
 
 // Get sends a GET request with optional query parameters and headers.
 func (c *Client) Get(baseURL string, params, headers map[string]string) (*Response, error) {
-	return c.executeRequest(http.MethodGet, baseURL, params, headers, nil)
+	return c.GetCtx(context.Background(), baseURL, params, headers)
+}
+
+// GetCtx is Get bound to ctx.
+func (c *Client) GetCtx(ctx context.Context, baseURL string, params, headers map[string]string) (*Response, error) {
+	return c.SendCtx(ctx, http.MethodGet, baseURL, params, headers, nil, "")
 }
 
 // Post sends a POST request with optional query parameters, headers, and body.
 func (c *Client) Post(baseURL string, params, headers map[string]string, payload io.Reader, contentType string) (*Response, error) {
-	if headers == nil {
-		headers = make(map[string]string)
-	}
-	if contentType != "" {
-		headers["Content-Type"] = contentType
-	}
-	return c.executeRequest(http.MethodPost, baseURL, params, headers, payload)
+	return c.PostCtx(context.Background(), baseURL, params, headers, payload, contentType)
+}
+
+// PostCtx is Post bound to ctx.
+func (c *Client) PostCtx(ctx context.Context, baseURL string, params, headers map[string]string, payload io.Reader, contentType string) (*Response, error) {
+	return c.SendCtx(ctx, http.MethodPost, baseURL, params, headers, payload, contentType)
+}
+
+// Put sends a PUT request with optional query parameters, headers, and body.
+func (c *Client) Put(baseURL string, params, headers map[string]string, payload io.Reader, contentType string) (*Response, error) {
+	return c.PutCtx(context.Background(), baseURL, params, headers, payload, contentType)
+}
+
+// PutCtx is Put bound to ctx.
+func (c *Client) PutCtx(ctx context.Context, baseURL string, params, headers map[string]string, payload io.Reader, contentType string) (*Response, error) {
+	return c.SendCtx(ctx, http.MethodPut, baseURL, params, headers, payload, contentType)
+}
+
+// Patch sends a PATCH request with optional query parameters, headers, and body.
+func (c *Client) Patch(baseURL string, params, headers map[string]string, payload io.Reader, contentType string) (*Response, error) {
+	return c.PatchCtx(context.Background(), baseURL, params, headers, payload, contentType)
+}
+
+// PatchCtx is Patch bound to ctx.
+func (c *Client) PatchCtx(ctx context.Context, baseURL string, params, headers map[string]string, payload io.Reader, contentType string) (*Response, error) {
+	return c.SendCtx(ctx, http.MethodPatch, baseURL, params, headers, payload, contentType)
+}
+
+// Delete sends a DELETE request with optional query parameters and headers.
+// Use Send for a DELETE with a body.
+func (c *Client) Delete(baseURL string, params, headers map[string]string) (*Response, error) {
+	return c.DeleteCtx(context.Background(), baseURL, params, headers)
+}
+
+// DeleteCtx is Delete bound to ctx.
+func (c *Client) DeleteCtx(ctx context.Context, baseURL string, params, headers map[string]string) (*Response, error) {
+	return c.SendCtx(ctx, http.MethodDelete, baseURL, params, headers, nil, "")
+}
+
+// Head sends a HEAD request with optional query parameters and headers.
+// The response has headers only.
+func (c *Client) Head(baseURL string, params, headers map[string]string) (*Response, error) {
+	return c.HeadCtx(context.Background(), baseURL, params, headers)
+}
+
+// HeadCtx is Head bound to ctx.
+func (c *Client) HeadCtx(ctx context.Context, baseURL string, params, headers map[string]string) (*Response, error) {
+	return c.SendCtx(ctx, http.MethodHead, baseURL, params, headers, nil, "")
+}
+
+// Options sends an OPTIONS request with optional query parameters and headers.
+func (c *Client) Options(baseURL string, params, headers map[string]string) (*Response, error) {
+	return c.OptionsCtx(context.Background(), baseURL, params, headers)
+}
+
+// OptionsCtx is Options bound to ctx.
+func (c *Client) OptionsCtx(ctx context.Context, baseURL string, params, headers map[string]string) (*Response, error) {
+	return c.SendCtx(ctx, http.MethodOptions, baseURL, params, headers, nil, "")
+}
+
+// PostJSON sends body encoded as JSON in a POST request with optional
+// headers. Use c.R() for a context or query parameters.
+func (c *Client) PostJSON(baseURL string, body any, headers map[string]string) (*Response, error) {
+	return c.R().SetHeaders(headers).SetBodyJSON(body).Post(baseURL)
+}
+
+// PutJSON sends body encoded as JSON in a PUT request with optional headers.
+func (c *Client) PutJSON(baseURL string, body any, headers map[string]string) (*Response, error) {
+	return c.R().SetHeaders(headers).SetBodyJSON(body).Put(baseURL)
+}
+
+// PatchJSON sends body encoded as JSON in a PATCH request with optional
+// headers.
+func (c *Client) PatchJSON(baseURL string, body any, headers map[string]string) (*Response, error) {
+	return c.R().SetHeaders(headers).SetBodyJSON(body).Patch(baseURL)
 }
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Decoder decompresses a response body with a given Content-Encoding.
+type Decoder func(r io.Reader) (io.ReadCloser, error)
+
+// builtinDecoders are the encodings decoded without WithDecoder.
+var builtinDecoders = map[string]Decoder{
+	"gzip":    func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	"deflate": zlib.NewReader,
+}
+
+// WithAcceptEncoding sends "Accept-Encoding" with encodings (defaults to
+// "gzip" and "deflate") on requests that don't set it. Compressed
+// responses are decoded transparently; see Request.SetDecompress for the
+// raw body. Without it, net/http still requests and decodes gzip itself.
+func WithAcceptEncoding(encodings ...string) Option {
+	if len(encodings) == 0 {
+		encodings = []string{"gzip", "deflate"}
+	}
+	return func(c *Client) { c.acceptEncoding = strings.Join(encodings, ", ") }
+}
+
+// WithDecoder registers a decoder for another Content-Encoding, e.g. "br"
+// or "zstd" from a third-party package; list it in WithAcceptEncoding to
+// request it.
+func WithDecoder(encoding string, d Decoder) Option {
+	return func(c *Client) {
+		if c.decoders == nil {
+			c.decoders = map[string]Decoder{}
+		}
+		c.decoders[strings.ToLower(encoding)] = d
+	}
+}
+
+// decoder returns the decoder for a Content-Encoding, or nil.
+func (c *Client) decoder(encoding string) Decoder {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	if d := c.decoders[encoding]; d != nil {
+		return d
+	}
+	return builtinDecoders[encoding]
+}
+
+// decompress replaces a compressed body by its decoded form, like
+// net/http's transparent gzip: Content-Encoding and Content-Length are
+// removed and Uncompressed is set. Unknown or stacked encodings are left
+// as is.
+func (c *Client) decompress(resp *http.Response) error {
+	encoding := resp.Header.Get("Content-Encoding")
+	if encoding == "" || resp.Body == nil || resp.Body == http.NoBody || resp.Request.Method == http.MethodHead {
+		return nil
+	}
+	d := c.decoder(encoding)
+	if d == nil {
+		return nil
+	}
+	decoded, err := d(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return fmt.Errorf("decode %s body: %w", encoding, err)
+	}
+	resp.Body = &decodedBody{ReadCloser: decoded, raw: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// decodedBody closes both the decoder and the underlying body.
+type decodedBody struct {
+	io.ReadCloser
+	raw io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	b.ReadCloser.Close()
+	return b.raw.Close()
+}
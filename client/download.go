@@ -0,0 +1,134 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SaveOption configures Response.SaveTo and Client.Download.
+type SaveOption func(s *saveConfig)
+
+type saveConfig struct {
+	progress func(written, total int64)
+	hash     hash.Hash
+	sum      string
+	err      error
+}
+
+// WithProgress calls fn as the body is written, with the bytes written so
+// far and the Content-Length (-1 if unknown).
+func WithProgress(fn func(written, total int64)) SaveOption {
+	return func(s *saveConfig) { s.progress = fn }
+}
+
+// WithChecksum verifies the body against a hex digest; algorithm is
+// "sha256" or "sha512". On a mismatch the file is not kept.
+func WithChecksum(algorithm, sum string) SaveOption {
+	return func(s *saveConfig) {
+		switch strings.ToLower(algorithm) {
+		case "sha256":
+			s.hash = sha256.New()
+		case "sha512":
+			s.hash = sha512.New()
+		default:
+			s.err = fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+		}
+		s.sum = strings.ToLower(sum)
+	}
+}
+
+// SaveTo streams the response body to the file at path without buffering
+// it in memory. The body goes to a temporary file next to path that
+// replaces it only once complete: the Content-Length, if any, and the
+// checksum, if requested, must match.
+func (r *Response) SaveTo(path string, opts ...SaveOption) (err error) {
+	var cfg saveConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.err != nil {
+		return cfg.err
+	}
+	if r == nil || r.Body == nil {
+		return fmt.Errorf("nil response or body")
+	}
+	defer r.Body.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.part")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	w := io.Writer(tmp)
+	if cfg.hash != nil {
+		w = io.MultiWriter(tmp, cfg.hash)
+	}
+	if cfg.progress != nil {
+		w = &progressWriter{w: w, total: r.ContentLength, fn: cfg.progress}
+	}
+	n, err := io.Copy(w, r.Body)
+	if err != nil {
+		return err
+	}
+	if r.ContentLength >= 0 && n != r.ContentLength {
+		return fmt.Errorf("incomplete body: got %d of %d bytes", n, r.ContentLength)
+	}
+	if cfg.hash != nil {
+		if got := hex.EncodeToString(cfg.hash.Sum(nil)); got != cfg.sum {
+			return fmt.Errorf("checksum mismatch: got %s, want %s", got, cfg.sum)
+		}
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Download GETs rawURL and saves the body to path like SaveTo, reporting
+// progress to onProgress if set. A non-2xx response yields a *StatusError
+// and leaves path untouched.
+func (c *Client) Download(rawURL, path string, onProgress func(written, total int64), opts ...SaveOption) (*Response, error) {
+	resp, err := c.R().Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsSuccess() {
+		resp.getDataCopy()
+		return resp, &StatusError{Response: resp}
+	}
+	if onProgress != nil {
+		opts = append(opts, WithProgress(onProgress))
+	}
+	return resp, resp.SaveTo(path, opts...)
+}
+
+// progressWriter reports bytes written.
+type progressWriter struct {
+	w       io.Writer
+	written int64
+	total   int64
+	fn      func(written, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.fn(p.written, p.total)
+	return n, err
+}
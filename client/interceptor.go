@@ -0,0 +1,64 @@
+package client
+
+import "net/http"
+
+// RoundTripFunc sends a single HTTP request, like http.RoundTripper.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (fn RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return fn(req) }
+
+// Interceptor is client-side middleware, the counterpart of the server's
+// Step: it wraps the round trip of every request (redirects and retries
+// included) to add headers, log, record metrics and so on. It must not
+// modify the request in place; clone it first.
+//
+//	func userAgent(next client.RoundTripFunc) client.RoundTripFunc {
+//		return func(req *http.Request) (*http.Response, error) {
+//			req = req.Clone(req.Context())
+//			req.Header.Set("User-Agent", "orders/1.4")
+//			return next(req)
+//		}
+//	}
+type Interceptor func(next RoundTripFunc) RoundTripFunc
+
+// WithInterceptors adds interceptors to the client; see Use.
+func WithInterceptors(in ...Interceptor) Option {
+	return func(c *Client) { c.Use(in...) }
+}
+
+// Use adds interceptors around the client's Transport. They run in the
+// order added, the first outermost, like Steps. Set a custom Transport
+// before adding interceptors, as assigning one replaces them.
+func (c *Client) Use(in ...Interceptor) {
+	chain, ok := c.Transport.(*interceptorTransport)
+	if !ok {
+		chain = &interceptorTransport{base: c.Transport}
+		c.Transport = chain
+	}
+	chain.interceptors = append(chain.interceptors, in...)
+	chain.build()
+}
+
+// interceptorTransport runs interceptors around a base RoundTripper.
+type interceptorTransport struct {
+	base         http.RoundTripper
+	interceptors []Interceptor
+	rt           RoundTripFunc
+}
+
+func (t *interceptorTransport) build() {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	rt := RoundTripFunc(base.RoundTrip)
+	for i := len(t.interceptors) - 1; i >= 0; i-- {
+		rt = t.interceptors[i](rt)
+	}
+	t.rt = rt
+}
+
+func (t *interceptorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.rt(req)
+}
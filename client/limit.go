@@ -0,0 +1,64 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrResponseTooLarge is returned by body reads past the limit set with
+// WithMaxResponseSize.
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// WithMaxResponseSize limits response bodies to n bytes: reading more, or
+// a body declaring a larger Content-Length, fails with
+// ErrResponseTooLarge. It guards against upstreams returning unbounded
+// bodies. Zero means no limit; override it per request with
+// Request.SetMaxResponseSize.
+func WithMaxResponseSize(n int64) Option {
+	return func(c *Client) { c.maxResponse = n }
+}
+
+// limitedBody fails reads beyond limit bytes.
+type limitedBody struct {
+	io.ReadCloser
+	limit     int64
+	remaining int64
+}
+
+func limitBody(body io.ReadCloser, contentLength, limit int64) io.ReadCloser {
+	if limit <= 0 {
+		return body
+	}
+	if contentLength > limit {
+		return &limitedBody{ReadCloser: body, limit: limit, remaining: -1}
+	}
+	return &limitedBody{ReadCloser: body, limit: limit, remaining: limit}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, b.tooLarge()
+	}
+	if b.remaining == 0 {
+		// Read one more byte to tell a body of exactly limit bytes apart
+		// from a longer one.
+		var one [1]byte
+		n, err := b.ReadCloser.Read(one[:])
+		if n > 0 {
+			b.remaining = -1
+			return 0, b.tooLarge()
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) tooLarge() error {
+	return fmt.Errorf("%w: limit is %d bytes", ErrResponseTooLarge, b.limit)
+}
@@ -0,0 +1,292 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// redacted replaces secret header and query values in logs.
+const redacted = "[REDACTED]"
+
+// sensitiveHeaders are always redacted by RedactHeaders.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// sensitiveFields are always redacted in logged JSON and form bodies and
+// in query parameters.
+var sensitiveFields = []string{
+	"password", "passwd", "secret", "client_secret", "token", "access_token",
+	"refresh_token", "id_token", "api_key", "apikey", "private_key",
+}
+
+// LogConfig configures WithLogging.
+type LogConfig struct {
+	// Logger receives one record per round trip (defaults to slog.Default()).
+	Logger *slog.Logger
+	// Level of successful round trips (slog.LevelInfo when zero);
+	// transport errors are logged at slog.LevelWarn or Level if higher.
+	Level slog.Level
+	// Headers logs request and response headers.
+	Headers bool
+	// BodyLimit logs up to this many bytes of each body; 0 logs none.
+	// Response bodies are read ahead to log them, which delays streamed
+	// responses until BodyLimit bytes arrive. Compressed bodies are not
+	// logged.
+	BodyLimit int
+	// Redact lists more headers to redact, beyond Authorization,
+	// Proxy-Authorization, Cookie, Set-Cookie and X-Api-Key.
+	Redact []string
+	// RedactQuery lists query parameters to redact, beyond the fields
+	// redacted in bodies.
+	RedactQuery []string
+	// RedactFields lists more JSON and form body fields to redact, beyond
+	// password, secret, client_secret, token, access_token, refresh_token,
+	// id_token, api_key and similar. Names match case-insensitively at any
+	// depth, and query parameters of the same names are redacted too.
+	// Multipart bodies are never logged.
+	RedactFields []string
+}
+
+// WithLogging logs every round trip, redirects and retries included:
+// method, URL, status and duration, plus headers and truncated bodies as
+// cfg asks, with secrets redacted. For troubleshooting integrations.
+func WithLogging(cfg LogConfig) Option {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	fields := make(map[string]bool, len(sensitiveFields)+len(cfg.RedactFields))
+	for _, names := range [][]string{sensitiveFields, cfg.RedactFields} {
+		for _, name := range names {
+			fields[strings.ToLower(name)] = true
+		}
+	}
+	query := maps.Clone(fields)
+	for _, name := range cfg.RedactQuery {
+		query[strings.ToLower(name)] = true
+	}
+	logBody := func(key string, b []byte, contentType string) slog.Attr {
+		truncated := len(b) > cfg.BodyLimit
+		if truncated {
+			b = b[:cfg.BodyLimit]
+		}
+		return bodyAttr(key, redactBody(b, truncated, contentType, fields), truncated)
+	}
+	return WithInterceptors(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			if !cfg.Logger.Enabled(ctx, cfg.Level) {
+				return next(req)
+			}
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("url", redactURL(req.URL, query)),
+			}
+			if cfg.Headers {
+				attrs = append(attrs, headerAttr("request_headers", RedactHeaders(req.Header, cfg.Redact...)))
+			}
+			if cfg.BodyLimit > 0 && req.GetBody != nil {
+				if body, err := req.GetBody(); err == nil {
+					b, _ := io.ReadAll(io.LimitReader(body, int64(cfg.BodyLimit)+1))
+					body.Close()
+					attrs = append(attrs, logBody("request_body", b, req.Header.Get("Content-Type")))
+				}
+			}
+
+			start := time.Now()
+			resp, err := next(req)
+			attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+				cfg.Logger.LogAttrs(ctx, max(cfg.Level, slog.LevelWarn), "http request failed", attrs...)
+				return resp, err
+			}
+			attrs = append(attrs, slog.Int("status", resp.StatusCode), slog.String("proto", resp.Proto))
+			if cfg.Headers {
+				attrs = append(attrs, headerAttr("response_headers", RedactHeaders(resp.Header, cfg.Redact...)))
+			}
+			if enc := resp.Header.Get("Content-Encoding"); cfg.BodyLimit > 0 && enc != "" && enc != "identity" {
+				attrs = append(attrs, slog.String("response_body", "["+enc+" encoded]"))
+			} else if cfg.BodyLimit > 0 {
+				b := peekBody(resp, cfg.BodyLimit)
+				attrs = append(attrs, logBody("response_body", b, resp.Header.Get("Content-Type")))
+			}
+			cfg.Logger.LogAttrs(ctx, cfg.Level, "http request", attrs...)
+			return resp, nil
+		}
+	})
+}
+
+// OnRequest calls fn before every round trip, redirects and retries
+// included. fn must not modify req. Use RedactHeaders before logging its
+// headers.
+func OnRequest(fn func(req *http.Request)) Option {
+	return WithInterceptors(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			fn(req)
+			return next(req)
+		}
+	})
+}
+
+// OnResponse calls fn after every round trip with its response or error
+// and the time until the response headers arrived. fn must not read the
+// response body.
+func OnResponse(fn func(req *http.Request, resp *http.Response, err error, elapsed time.Duration)) Option {
+	return WithInterceptors(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			fn(req, resp, err, time.Since(start))
+			return resp, err
+		}
+	})
+}
+
+// RedactHeaders returns a copy of h with the values of Authorization,
+// Proxy-Authorization, Cookie, Set-Cookie, X-Api-Key and the extra headers
+// replaced by "[REDACTED]".
+func RedactHeaders(h http.Header, extra ...string) http.Header {
+	out := h.Clone()
+	if out == nil {
+		return http.Header{}
+	}
+	for _, names := range [][]string{sensitiveHeaders, extra} {
+		for _, name := range names {
+			if _, ok := out[http.CanonicalHeaderKey(name)]; ok {
+				out.Set(name, redacted)
+			}
+		}
+	}
+	return out
+}
+
+// redactURL returns u without its password and with the query parameters
+// in names, which are lowercase, redacted.
+func redactURL(u *url.URL, names map[string]bool) string {
+	if u.RawQuery == "" {
+		return u.Redacted()
+	}
+	q := u.Query()
+	found := false
+	for name, values := range q {
+		if names[strings.ToLower(name)] {
+			for i := range values {
+				values[i] = redacted
+			}
+			found = true
+		}
+	}
+	if !found {
+		return u.Redacted()
+	}
+	cp := *u
+	cp.RawQuery = q.Encode()
+	return cp.Redacted()
+}
+
+// peekBody reads up to limit+1 bytes of resp.Body and puts them back in
+// front of the rest; a read error shows up again when the body is read.
+func peekBody(resp *http.Response, limit int) []byte {
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+	resp.Body = &peekedBody{Reader: io.MultiReader(bytes.NewReader(b), resp.Body), Closer: resp.Body}
+	return b
+}
+
+// peekedBody replays the peeked bytes, then the rest of the body.
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+func headerAttr(key string, h http.Header) slog.Attr {
+	attrs := make([]any, 0, len(h))
+	for name, values := range h {
+		if len(values) == 1 {
+			attrs = append(attrs, slog.String(name, values[0]))
+		} else {
+			attrs = append(attrs, slog.Any(name, values))
+		}
+	}
+	return slog.Group(key, attrs...)
+}
+
+// redactBody redacts fields in a JSON or urlencoded body; other bodies
+// pass unchanged. A truncated JSON body can't be decoded, so its string
+// members are redacted by pattern instead.
+func redactBody(b []byte, truncated bool, contentType string, fields map[string]bool) []byte {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case len(b) == 0:
+		return b
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return []byte("[" + mediaType + " body]")
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(b))
+		if err != nil {
+			return []byte("[unparsable form body]")
+		}
+		for name := range values {
+			if fields[strings.ToLower(name)] {
+				values[name] = []string{redacted}
+			}
+		}
+		return []byte(values.Encode())
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var v any
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if !truncated && dec.Decode(&v) == nil {
+			if out, err := json.Marshal(redactJSON(v, fields)); err == nil {
+				return out
+			}
+		}
+		return jsonField.ReplaceAllFunc(b, func(m []byte) []byte {
+			name := jsonField.FindSubmatch(m)[1]
+			if !fields[strings.ToLower(string(name))] {
+				return m
+			}
+			return []byte(`"` + string(name) + `":"` + redacted + `"`)
+		})
+	}
+	return b
+}
+
+// jsonField matches a JSON string member, even one cut off by truncation.
+var jsonField = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"\s*:\s*"(?:[^"\\]|\\.)*(?:"|$)`)
+
+// redactJSON replaces the values of fields in decoded JSON, at any depth.
+func redactJSON(v any, fields map[string]bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if fields[strings.ToLower(k)] {
+				v[k] = redacted
+			} else {
+				v[k] = redactJSON(val, fields)
+			}
+		}
+	case []any:
+		for i, val := range v {
+			v[i] = redactJSON(val, fields)
+		}
+	}
+	return v
+}
+
+func bodyAttr(key string, b []byte, truncated bool) slog.Attr {
+	if truncated {
+		return slog.String(key, string(b)+"…")
+	}
+	return slog.String(key, string(b))
+}
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricsConfig configures NewMetrics.
+type MetricsConfig struct {
+	// Namespace prefixes the metric names, e.g. "orders" gives
+	// orders_http_client_requests_total.
+	Namespace string
+	// Buckets are the duration histogram's upper bounds in seconds
+	// (defaults to Prometheus' default buckets, 5ms to 10s).
+	Buckets []float64
+}
+
+// Metrics records outgoing requests in the Prometheus text format:
+//
+//	http_client_requests_total{host, method, status}
+//	http_client_request_duration_seconds{host, method, status} (histogram)
+//	http_client_requests_in_flight{host, method}
+//
+// status is "error" for requests that got no response. Durations run
+// until the response headers arrive. One Metrics may be shared by several
+// clients. Serve it as /metrics, or append WritePrometheus to the output
+// of an existing metrics endpoint so server and client metrics are
+// scraped together.
+type Metrics struct {
+	names    [3]string
+	buckets  []float64
+	mu       sync.Mutex
+	requests map[metricKey]*histogram
+	inFlight map[metricKey]int64
+}
+
+type metricKey struct{ host, method, status string }
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative; the last is +Inf
+	sum    float64
+	total  uint64
+}
+
+// NewMetrics creates an empty Metrics; add it to clients with
+// WithMetrics.
+func NewMetrics(cfg MetricsConfig) *Metrics {
+	if len(cfg.Buckets) == 0 {
+		cfg.Buckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	}
+	prefix := ""
+	if cfg.Namespace != "" {
+		prefix = cfg.Namespace + "_"
+	}
+	buckets := slices.Clone(cfg.Buckets)
+	slices.Sort(buckets)
+	return &Metrics{
+		names: [3]string{
+			prefix + "http_client_requests_total",
+			prefix + "http_client_request_duration_seconds",
+			prefix + "http_client_requests_in_flight",
+		},
+		buckets:  buckets,
+		requests: map[metricKey]*histogram{},
+		inFlight: map[metricKey]int64{},
+	}
+}
+
+// WithMetrics records the client's requests, redirects and retries
+// included, in m.
+func WithMetrics(m *Metrics) Option {
+	return WithInterceptors(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			flying := metricKey{host: req.URL.Host, method: req.Method}
+			m.mu.Lock()
+			m.inFlight[flying]++
+			m.mu.Unlock()
+
+			start := time.Now()
+			resp, err := next(req)
+			elapsed := time.Since(start).Seconds()
+
+			key := flying
+			key.status = "error"
+			if err == nil {
+				key.status = strconv.Itoa(resp.StatusCode)
+			}
+			m.mu.Lock()
+			m.inFlight[flying]--
+			m.observe(key, elapsed)
+			m.mu.Unlock()
+			return resp, err
+		}
+	})
+}
+
+func (m *Metrics) observe(key metricKey, seconds float64) {
+	h := m.requests[key]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(m.buckets)+1)}
+		m.requests[key] = h
+	}
+	i, _ := slices.BinarySearch(m.buckets, seconds)
+	h.counts[i]++
+	h.sum += seconds
+	h.total++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WritePrometheus(w)
+}
+
+// WritePrometheus writes the metrics to w in the Prometheus text format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	keys := make([]metricKey, 0, len(m.requests))
+	hists := make(map[metricKey]histogram, len(m.requests))
+	for k, h := range m.requests {
+		keys = append(keys, k)
+		hists[k] = histogram{counts: slices.Clone(h.counts), sum: h.sum, total: h.total}
+	}
+	flying := make([]metricKey, 0, len(m.inFlight))
+	gauges := make(map[metricKey]int64, len(m.inFlight))
+	for k, n := range m.inFlight {
+		flying = append(flying, k)
+		gauges[k] = n
+	}
+	m.mu.Unlock()
+	sortKeys(keys)
+	sortKeys(flying)
+
+	bw := bufio.NewWriter(w)
+	total, duration, inFlight := m.names[0], m.names[1], m.names[2]
+
+	bw.WriteString("# HELP " + total + " Outgoing HTTP requests.\n# TYPE " + total + " counter\n")
+	for _, k := range keys {
+		bw.WriteString(total + k.labels("") + " " + strconv.FormatUint(hists[k].total, 10) + "\n")
+	}
+
+	bw.WriteString("# HELP " + duration + " Outgoing HTTP request duration until response headers.\n# TYPE " + duration + " histogram\n")
+	for _, k := range keys {
+		h := hists[k]
+		var cumulative uint64
+		for i, le := range m.buckets {
+			cumulative += h.counts[i]
+			bound := `,le="` + strconv.FormatFloat(le, 'g', -1, 64) + `"`
+			bw.WriteString(duration + "_bucket" + k.labels(bound) + " " + strconv.FormatUint(cumulative, 10) + "\n")
+		}
+		bw.WriteString(duration + "_bucket" + k.labels(`,le="+Inf"`) + " " + strconv.FormatUint(h.total, 10) + "\n")
+		bw.WriteString(duration + "_sum" + k.labels("") + " " + strconv.FormatFloat(h.sum, 'g', -1, 64) + "\n")
+		bw.WriteString(duration + "_count" + k.labels("") + " " + strconv.FormatUint(h.total, 10) + "\n")
+	}
+
+	bw.WriteString("# HELP " + inFlight + " Outgoing HTTP requests awaiting a response.\n# TYPE " + inFlight + " gauge\n")
+	for _, k := range flying {
+		bw.WriteString(inFlight + k.labels("") + " " + strconv.FormatInt(gauges[k], 10) + "\n")
+	}
+	return bw.Flush()
+}
+
+// labels renders k's labels, status only when set, followed by extra.
+func (k metricKey) labels(extra string) string {
+	s := `{host="` + escapeLabel(k.host) + `",method="` + escapeLabel(k.method) + `"`
+	if k.status != "" {
+		s += `,status="` + k.status + `"`
+	}
+	return s + extra + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string { return labelEscaper.Replace(v) }
+
+func sortKeys(keys []metricKey) {
+	slices.SortFunc(keys, func(a, b metricKey) int {
+		return strings.Compare(a.host+" "+a.method+" "+a.status, b.host+" "+b.method+" "+b.status)
+	})
+}
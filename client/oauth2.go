@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuth2Config configures an OAuth2 token source.
+type OAuth2Config struct {
+	// TokenURL is the authorization server's token endpoint.
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// RefreshToken, if set, selects the refresh_token grant instead of
+	// client_credentials. Rotated refresh tokens are kept; with
+	// client_credentials, returned refresh tokens are ignored and a new
+	// token is requested instead.
+	RefreshToken string
+	// Params are extra token request parameters, e.g. "audience".
+	Params map[string]string
+	// CredentialsInBody sends the client credentials as form parameters
+	// instead of HTTP Basic auth, for servers that require it.
+	CredentialsInBody bool
+	// Leeway is how long before expiry a token is renewed (defaults to 30s).
+	Leeway time.Duration
+	// Client sends token requests (defaults to a client with a 10s timeout).
+	Client *Client
+}
+
+// OAuth2TokenSource is a TokenSource that obtains access tokens from an
+// OAuth2 token endpoint and renews them before they expire. Concurrent
+// callers share one token request.
+type OAuth2TokenSource struct {
+	cfg OAuth2Config
+
+	mu       sync.Mutex
+	token    string
+	expiry   time.Time // zero: doesn't expire
+	refresh  string
+	inflight chan struct{}
+	err      error
+}
+
+// NewOAuth2TokenSource creates a token source for cfg; use it with
+// c.SetTokenSource.
+func NewOAuth2TokenSource(cfg OAuth2Config) *OAuth2TokenSource {
+	if cfg.Leeway <= 0 {
+		cfg.Leeway = 30 * time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = NewClient(10 * time.Second)
+	}
+	return &OAuth2TokenSource{cfg: cfg, refresh: cfg.RefreshToken}
+}
+
+// Token returns a valid access token, fetching a new one if needed.
+func (s *OAuth2TokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	if s.token != "" && (s.expiry.IsZero() || time.Now().Before(s.expiry.Add(-s.cfg.Leeway))) {
+		token := s.token
+		s.mu.Unlock()
+		return token, nil
+	}
+	wait := s.inflight
+	if wait == nil {
+		wait = make(chan struct{})
+		s.inflight = wait
+		go s.fetch(context.WithoutCancel(ctx), wait)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-wait:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return "", s.err
+	}
+	return s.token, nil
+}
+
+// Invalidate drops the cached token, e.g. after a 401, so the next Token
+// call fetches a new one.
+func (s *OAuth2TokenSource) Invalidate() {
+	s.mu.Lock()
+	s.token = ""
+	s.mu.Unlock()
+}
+
+// fetch requests a token and wakes the callers waiting on done.
+func (s *OAuth2TokenSource) fetch(ctx context.Context, done chan struct{}) {
+	s.mu.Lock()
+	refresh := s.refresh
+	s.mu.Unlock()
+
+	tok, err := s.request(ctx, refresh)
+
+	s.mu.Lock()
+	s.err = err
+	if err == nil {
+		s.token = tok.AccessToken
+		s.expiry = time.Time{}
+		if tok.ExpiresIn > 0 {
+			s.expiry = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+		}
+		if s.refresh != "" && tok.RefreshToken != "" {
+			s.refresh = tok.RefreshToken
+		}
+	}
+	s.inflight = nil
+	s.mu.Unlock()
+	close(done)
+}
+
+// oauth2Token is a token endpoint response (RFC 6749 section 5.1).
+type oauth2Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+func (s *OAuth2TokenSource) request(ctx context.Context, refresh string) (*oauth2Token, error) {
+	form := url.Values{}
+	if refresh != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", refresh)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+	if len(s.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	}
+	for k, v := range s.cfg.Params {
+		form.Set(k, v)
+	}
+	r := s.cfg.Client.R().SetContext(ctx).SetHeader("Accept", "application/json")
+	if s.cfg.CredentialsInBody {
+		form.Set("client_id", s.cfg.ClientID)
+		form.Set("client_secret", s.cfg.ClientSecret)
+	} else {
+		creds := url.QueryEscape(s.cfg.ClientID) + ":" + url.QueryEscape(s.cfg.ClientSecret)
+		r.SetHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(creds)))
+	}
+
+	resp, err := r.SetFormData(form).Post(s.cfg.TokenURL)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2 token request: %w", err)
+	}
+	var tok oauth2Token
+	if err := resp.JSONInto(&tok); err != nil && resp.IsSuccess() {
+		return nil, fmt.Errorf("oauth2 token response: %w", err)
+	}
+	if !resp.IsSuccess() || tok.AccessToken == "" {
+		msg := tok.Error
+		if tok.Description != "" {
+			msg += ": " + tok.Description
+		}
+		if msg == "" {
+			msg = resp.StatusText()
+		}
+		return nil, fmt.Errorf("oauth2 token request: %d %s", resp.StatusCode, msg)
+	}
+	if tok.TokenType != "" && !strings.EqualFold(tok.TokenType, "bearer") {
+		return nil, fmt.Errorf("oauth2 token response: unsupported token type %q", tok.TokenType)
+	}
+	return &tok, nil
+}
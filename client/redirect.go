@@ -0,0 +1,68 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// RedirectPolicy configures how the client follows redirects.
+type RedirectPolicy struct {
+	// Max is how many redirects are followed before failing (defaults
+	// to 10).
+	Max int
+	// Disable returns redirect responses as they are instead of following
+	// them.
+	Disable bool
+	// KeepMethod resends the original method and body on 301, 302 and 303
+	// instead of switching to GET; 307 and 308 always keep them. Requests
+	// whose body can't be replayed switch to GET as usual.
+	KeepMethod bool
+	// KeepHeaders resends every header of the original request, including
+	// Authorization and Cookie, which net/http drops on redirects to
+	// another host. Only use it with hosts you trust.
+	KeepHeaders bool
+	// OnRedirect, if set, is called before each hop with the next request
+	// and those made so far, oldest first, e.g. to log or veto it.
+	// Returning http.ErrUseLastResponse stops and returns the redirect
+	// response; another error fails the request.
+	OnRedirect func(req *http.Request, via []*http.Request) error
+}
+
+// WithRedirectPolicy replaces net/http's default redirect handling.
+func WithRedirectPolicy(p RedirectPolicy) Option {
+	if p.Max <= 0 {
+		p.Max = 10
+	}
+	return func(c *Client) { c.CheckRedirect = p.check }
+}
+
+func (p RedirectPolicy) check(req *http.Request, via []*http.Request) error {
+	if p.Disable {
+		return http.ErrUseLastResponse
+	}
+	if len(via) > p.Max {
+		return fmt.Errorf("stopped after %d redirects", p.Max)
+	}
+	first, prev := via[0], via[len(via)-1]
+	if p.KeepMethod && req.Method != prev.Method && (prev.Body == nil || prev.Body == http.NoBody || prev.GetBody != nil) {
+		req.Method = prev.Method
+		if prev.GetBody != nil {
+			body, err := prev.GetBody()
+			if err != nil {
+				return err
+			}
+			req.Body, req.GetBody, req.ContentLength = body, prev.GetBody, prev.ContentLength
+		}
+	}
+	if p.KeepHeaders {
+		for k, v := range first.Header {
+			if _, ok := req.Header[k]; !ok {
+				req.Header[k] = v
+			}
+		}
+	}
+	if p.OnRedirect != nil {
+		return p.OnRedirect(req, via)
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Request builds a single request fluently; create one with c.R():
+//
+//	resp, err := c.R().
+//		SetHeader("Authorization", "Bearer "+token).
+//		SetQuery("page", "2").
+//		SetBodyJSON(order).
+//		Post("https://api.example.com/orders")
+//
+// Setter errors (e.g. a value that can't be encoded) are reported by the
+// method sending the request. A Request is not safe for concurrent use.
+type Request struct {
+	client      *Client
+	ctx         context.Context
+	header      http.Header
+	query       url.Values
+	body        io.Reader
+	contentType string
+	noCache     bool
+	maxResponse int64
+	raw         bool
+	timeout     time.Duration
+	deadline    time.Time
+	err         error
+}
+
+// R starts a new Request on c.
+func (c *Client) R() *Request {
+	return &Request{client: c, ctx: context.Background(), header: http.Header{}, query: url.Values{}, noCache: c.noCache, maxResponse: c.maxResponse}
+}
+
+// SetContext binds the request to ctx, so canceling ctx aborts it.
+func (r *Request) SetContext(ctx context.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
+// SetHeader sets a header, replacing previous values.
+func (r *Request) SetHeader(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// AddHeader adds a header value, keeping previous ones.
+func (r *Request) AddHeader(key, value string) *Request {
+	r.header.Add(key, value)
+	return r
+}
+
+// SetHeaders sets several headers.
+func (r *Request) SetHeaders(headers map[string]string) *Request {
+	for k, v := range headers {
+		r.header.Set(k, v)
+	}
+	return r
+}
+
+// SetQuery sets a query parameter, replacing previous values and any
+// value already in the URL.
+func (r *Request) SetQuery(key, value string) *Request {
+	r.query.Set(key, value)
+	return r
+}
+
+// AddQuery adds a query parameter value, e.g. for "tag=a&tag=b".
+func (r *Request) AddQuery(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// SetQueryParams sets several query parameters.
+func (r *Request) SetQueryParams(params map[string]string) *Request {
+	for k, v := range params {
+		r.query.Set(k, v)
+	}
+	return r
+}
+
+// SetBody sets the request body and, if contentType is set, its
+// Content-Type.
+func (r *Request) SetBody(body io.Reader, contentType string) *Request {
+	r.body, r.contentType = body, contentType
+	return r
+}
+
+// SetBodyJSON encodes v as the JSON request body.
+func (r *Request) SetBodyJSON(v any) *Request {
+	b, err := json.Marshal(v)
+	if err != nil {
+		r.err = fmt.Errorf("encode JSON body: %w", err)
+		return r
+	}
+	return r.SetBody(bytes.NewReader(b), "application/json")
+}
+
+// SetFormData sets a URL-encoded form body.
+func (r *Request) SetFormData(form url.Values) *Request {
+	return r.SetBody(strings.NewReader(form.Encode()), "application/x-www-form-urlencoded")
+}
+
+// SetBodyCache sets whether the response keeps its body once read (the
+// client's setting by default); see WithoutBodyCache.
+func (r *Request) SetBodyCache(enabled bool) *Request {
+	r.noCache = !enabled
+	return r
+}
+
+// SetMaxResponseSize overrides the client's response size limit (see
+// WithMaxResponseSize) for this request; zero means no limit.
+func (r *Request) SetMaxResponseSize(n int64) *Request {
+	r.maxResponse = n
+	return r
+}
+
+// SetDecompress sets whether a compressed response body is decoded
+// (the default). Disable it to get the raw body, e.g. to store or proxy it
+// as is; Content-Encoding then tells how it is encoded.
+func (r *Request) SetDecompress(enabled bool) *Request {
+	r.raw = !enabled
+	return r
+}
+
+// SetTimeout overrides the client's Timeout for this request, shorter or
+// longer. Like it, it covers connecting, redirects and reading the body,
+// and applies to each retry separately.
+func (r *Request) SetTimeout(d time.Duration) *Request {
+	r.timeout = d
+	return r
+}
+
+// SetDeadline makes the request, body read included, fail at t instead of
+// after the client's Timeout.
+func (r *Request) SetDeadline(t time.Time) *Request {
+	r.deadline = t
+	return r
+}
+
+// Get sends the request as a GET to rawURL.
+func (r *Request) Get(rawURL string) (*Response, error) { return r.Send(http.MethodGet, rawURL) }
+
+// Post sends the request as a POST to rawURL.
+func (r *Request) Post(rawURL string) (*Response, error) { return r.Send(http.MethodPost, rawURL) }
+
+// Put sends the request as a PUT to rawURL.
+func (r *Request) Put(rawURL string) (*Response, error) { return r.Send(http.MethodPut, rawURL) }
+
+// Patch sends the request as a PATCH to rawURL.
+func (r *Request) Patch(rawURL string) (*Response, error) { return r.Send(http.MethodPatch, rawURL) }
+
+// Delete sends the request as a DELETE to rawURL.
+func (r *Request) Delete(rawURL string) (*Response, error) { return r.Send(http.MethodDelete, rawURL) }
+
+// Head sends the request as a HEAD to rawURL.
+func (r *Request) Head(rawURL string) (*Response, error) { return r.Send(http.MethodHead, rawURL) }
+
+// Options sends the request as an OPTIONS to rawURL.
+func (r *Request) Options(rawURL string) (*Response, error) {
+	return r.Send(http.MethodOptions, rawURL)
+}
+
+// Send sends the request with method to rawURL.
+func (r *Request) Send(method, rawURL string) (*Response, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	req, err := r.build(method, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	tm := newTimer()
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), tm.trace()))
+	hc := r.client.Client
+	if r.timeout > 0 || !r.deadline.IsZero() {
+		hc = r.httpClient()
+	}
+	resp, err := r.client.do(hc, req)
+	if err != nil {
+		return nil, err
+	}
+	tm.headers()
+	if !r.raw {
+		if err := r.client.decompress(resp); err != nil {
+			return nil, err
+		}
+	}
+	resp.Body = &timedBody{limitBody(resp.Body, resp.ContentLength, r.maxResponse), tm}
+	return &Response{Response: resp, disallowUnknown: r.client.disallowUnknown, noCache: r.noCache, timer: tm}, nil
+}
+
+// httpClient returns a copy of the client's http.Client with the request's
+// timeout or, if sooner, deadline.
+func (r *Request) httpClient() *http.Client {
+	hc := *r.client.Client
+	hc.Timeout = r.timeout
+	if !r.deadline.IsZero() {
+		until := max(time.Until(r.deadline), time.Nanosecond)
+		if hc.Timeout <= 0 || until < hc.Timeout {
+			hc.Timeout = until
+		}
+	}
+	return &hc
+}
+
+// build creates the *http.Request.
+func (r *Request) build(method, rawURL string) (*http.Request, error) {
+	c := r.client
+	if c.baseURL != "" && !strings.Contains(rawURL, "://") {
+		rawURL = c.baseURL + "/" + strings.TrimPrefix(rawURL, "/")
+	}
+	if len(r.query) > 0 || len(c.query) > 0 {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		q := u.Query()
+		for k, v := range c.query {
+			if _, ok := q[k]; !ok {
+				q[k] = v
+			}
+		}
+		for k, v := range r.query {
+			q[k] = v
+		}
+		u.RawQuery = q.Encode()
+		rawURL = u.String()
+	}
+	req, err := http.NewRequestWithContext(r.ctx, method, rawURL, r.body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	if req.Header.Get("Accept-Encoding") == "" {
+		switch {
+		case c.acceptEncoding != "":
+			req.Header.Set("Accept-Encoding", c.acceptEncoding)
+		case r.raw:
+			// Ask for gzip ourselves, or net/http would decode it.
+			req.Header.Set("Accept-Encoding", "gzip")
+		}
+	}
+	return req, nil
+}
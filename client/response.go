@@ -12,7 +12,11 @@ import (
 // for multiple reads and easier JSON/string parsing.
 type Response struct {
 	*http.Response
-	cachedBody []byte
+	cachedBody      []byte
+	disallowUnknown bool
+	noCache         bool
+	consumed        bool
+	timer           *timer
 }
 
 // getDataCopy safely reads the body once, closes it, and rebuilds it
@@ -24,11 +28,19 @@ func (resp *Response) getDataCopy() ([]byte, error) {
 	if resp.cachedBody != nil {
 		return resp.cachedBody, nil
 	}
+	if resp.consumed {
+		return nil, fmt.Errorf("response body already consumed")
+	}
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
+	if resp.noCache {
+		resp.Body.Close()
+		resp.consumed = true
+		return bodyBytes, nil
+	}
 
 	resp.Body.Close()
 	resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
@@ -37,6 +49,35 @@ func (resp *Response) getDataCopy() ([]byte, error) {
 	return bodyBytes, nil
 }
 
+// Reader returns the response body for streaming, e.g. server-sent
+// events, without reading it into memory. The caller must close it. After
+// that, the body can't be read again unless it was already cached by
+// Bytes, String or a JSON method.
+func (r *Response) Reader() (io.ReadCloser, error) {
+	if r == nil || r.Body == nil {
+		return nil, fmt.Errorf("nil response or body")
+	}
+	if r.cachedBody != nil {
+		return io.NopCloser(bytes.NewReader(r.cachedBody)), nil
+	}
+	if r.consumed {
+		return nil, fmt.Errorf("response body already consumed")
+	}
+	r.consumed = true
+	return r.Body, nil
+}
+
+// Stream copies the response body to w without buffering it, then closes
+// it, returning the bytes copied.
+func (r *Response) Stream(w io.Writer) (int64, error) {
+	body, err := r.Reader()
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	return io.Copy(w, body)
+}
+
 // Json parses the response body into a map[string]any.
 // Returns an error if the body is not valid JSON.
 func (r *Response) Json() (map[string]any, error) {
@@ -51,6 +92,31 @@ func (r *Response) Json() (map[string]any, error) {
 	return data, nil
 }
 
+// JSONInto decodes the response body into v, typically a pointer to a
+// struct. With WithDisallowUnknownFields, unknown object fields are an
+// error.
+func (r *Response) JSONInto(v any) error {
+	body, err := r.getDataCopy()
+	if err != nil {
+		return err
+	}
+	return r.decodeJSON(body, v)
+}
+
+func (r *Response) decodeJSON(body []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if r.disallowUnknown {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if dec.More() {
+		return fmt.Errorf("failed to parse JSON: unexpected data after top-level value")
+	}
+	return nil
+}
+
 // String returns the response body as a string.
 func (r *Response) String() (string, error) {
 	body, err := r.getDataCopy()
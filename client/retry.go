@@ -0,0 +1,107 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryConfig configures WithRetry.
+type RetryConfig struct {
+	// Max is how many times a request is retried (defaults to 3).
+	Max int
+	// Wait is the delay before the first retry, doubled for each further
+	// one, when the response has no Retry-After (defaults to 1s).
+	Wait time.Duration
+	// MaxWait caps the delay (defaults to 30s). A Retry-After asking for
+	// longer ends the retries, returning the response, rather than calling
+	// the server back early.
+	MaxWait time.Duration
+	// Statuses are the status codes retried (defaults to 429 and 503,
+	// which mean the request wasn't processed).
+	Statuses []int
+}
+
+// WithRetry retries requests answered with one of cfg.Statuses, honoring
+// the Retry-After header in both its seconds and HTTP-date forms. Requests
+// whose body can't be replayed (see http.Request.GetBody) aren't retried.
+func WithRetry(cfg RetryConfig) Option {
+	if cfg.Max <= 0 {
+		cfg.Max = 3
+	}
+	if cfg.Wait <= 0 {
+		cfg.Wait = time.Second
+	}
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = 30 * time.Second
+	}
+	if len(cfg.Statuses) == 0 {
+		cfg.Statuses = []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}
+	}
+	return func(c *Client) { c.retry = &cfg }
+}
+
+// do sends req with hc, retrying per the client's RetryConfig.
+func (c *Client) do(hc *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := hc.Do(req)
+	cfg := c.retry
+	if cfg == nil {
+		return resp, err
+	}
+	wait := cfg.Wait
+	for attempt := 0; attempt < cfg.Max; attempt++ {
+		if err != nil || !slices.Contains(cfg.Statuses, resp.StatusCode) {
+			return resp, err
+		}
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, nil
+		}
+		delay := wait
+		if after, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			delay = after
+		}
+		if delay > cfg.MaxWait {
+			return resp, nil
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		wait *= 2
+
+		next := req.Clone(req.Context())
+		if req.GetBody != nil {
+			if next.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		resp, err = hc.Do(next)
+	}
+	return resp, err
+}
+
+// retryAfter parses a Retry-After value: delay-seconds or an HTTP-date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	return max(t.Sub(now), 0), true
+}
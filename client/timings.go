@@ -0,0 +1,134 @@
+package client
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings break down where a request's time went, for diagnosing slow
+// upstreams. The phases describe the last attempt when there were
+// redirects or retries; phases that didn't happen, like DNS and Connect on
+// a reused connection, are zero.
+type Timings struct {
+	// DNS is the host lookup.
+	DNS time.Duration
+	// Connect is the TCP connect, DNS excluded.
+	Connect time.Duration
+	// TLSHandshake is the TLS handshake.
+	TLSHandshake time.Duration
+	// TTFB is from having written the request to the first response byte,
+	// roughly the server's processing time.
+	TTFB time.Duration
+	// Total runs from sending the request until the body was read or
+	// closed; before that, until the response headers arrived. It includes
+	// all redirects and retries.
+	Total time.Duration
+	// Reused reports whether the connection came from the pool.
+	Reused bool
+}
+
+// Timings returns the request's timing breakdown.
+func (r *Response) Timings() Timings {
+	if r == nil || r.timer == nil {
+		return Timings{}
+	}
+	return r.timer.timings()
+}
+
+// timer records the httptrace events of a request.
+type timer struct {
+	mu                                   sync.Mutex
+	start, dnsStart, connStart, tlsStart time.Time
+	wrote, end                           time.Time
+	t                                    Timings
+}
+
+func newTimer() *timer { return &timer{start: time.Now()} }
+
+// trace returns hooks filling in tm; each new connection attempt resets
+// the phases.
+func (tm *timer) trace() *httptrace.ClientTrace {
+	at := func(fn func(now time.Time)) {
+		now := time.Now()
+		tm.mu.Lock()
+		fn(now)
+		tm.mu.Unlock()
+	}
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			at(func(time.Time) { tm.t = Timings{} })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			at(func(time.Time) { tm.t.Reused = info.Reused })
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			at(func(now time.Time) { tm.dnsStart = now })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			at(func(now time.Time) { tm.t.DNS = now.Sub(tm.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			at(func(now time.Time) { tm.connStart = now })
+		},
+		ConnectDone: func(string, string, error) {
+			at(func(now time.Time) { tm.t.Connect = now.Sub(tm.connStart) })
+		},
+		TLSHandshakeStart: func() {
+			at(func(now time.Time) { tm.tlsStart = now })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			at(func(now time.Time) { tm.t.TLSHandshake = now.Sub(tm.tlsStart) })
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			at(func(now time.Time) { tm.wrote = now })
+		},
+		GotFirstResponseByte: func() {
+			at(func(now time.Time) { tm.t.TTFB = now.Sub(tm.wrote) })
+		},
+	}
+}
+
+// headers records the arrival of the final response's headers.
+func (tm *timer) headers() {
+	tm.mu.Lock()
+	tm.t.Total = time.Since(tm.start)
+	tm.mu.Unlock()
+}
+
+// finish records the end of the body, once.
+func (tm *timer) finish() {
+	tm.mu.Lock()
+	if tm.end.IsZero() {
+		tm.end = time.Now()
+		tm.t.Total = tm.end.Sub(tm.start)
+	}
+	tm.mu.Unlock()
+}
+
+func (tm *timer) timings() Timings {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.t
+}
+
+// timedBody calls tm.finish when the body is read to the end or closed.
+type timedBody struct {
+	io.ReadCloser
+	tm *timer
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.tm.finish()
+	}
+	return n, err
+}
+
+func (b *timedBody) Close() error {
+	b.tm.finish()
+	return b.ReadCloser.Close()
+}
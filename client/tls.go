@@ -0,0 +1,75 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// WithTLSConfig uses a copy of cfg for TLS connections, for settings the
+// other TLS options don't cover. Options after it adjust the copy, so cfg
+// itself can be shared.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *Client) { c.transport().TLSClientConfig = cfg.Clone() }
+}
+
+// WithRootCAs verifies servers against pool instead of the system roots,
+// e.g. for an internal CA.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(c *Client) { c.tlsConfig().RootCAs = pool }
+}
+
+// WithRootCAFile verifies servers against the PEM certificates in path
+// instead of the system roots. It panics if the file can't be read or
+// holds no certificate.
+func WithRootCAFile(path string) Option {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		panic(fmt.Errorf("client: root CAs: %v", err))
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		panic(fmt.Errorf("client: root CAs: no certificates in %s", path))
+	}
+	return WithRootCAs(pool)
+}
+
+// WithClientCertificate presents cert to servers asking for one (mutual
+// TLS).
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(c *Client) {
+		cfg := c.tlsConfig()
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+}
+
+// WithClientCertificateFile loads a PEM certificate and key for mutual
+// TLS. It panics if they can't be loaded.
+func WithClientCertificateFile(certFile, keyFile string) Option {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		panic(fmt.Errorf("client: client certificate: %v", err))
+	}
+	return WithClientCertificate(cert)
+}
+
+// WithMinTLSVersion refuses servers below version, e.g. tls.VersionTLS13.
+func WithMinTLSVersion(version uint16) Option {
+	return func(c *Client) { c.tlsConfig().MinVersion = version }
+}
+
+// WithInsecureSkipVerify accepts any server certificate. It defeats TLS
+// and is meant for local development only.
+func WithInsecureSkipVerify() Option {
+	return func(c *Client) { c.tlsConfig().InsecureSkipVerify = true }
+}
+
+// tlsConfig returns the transport's TLS config, creating it if needed.
+func (c *Client) tlsConfig() *tls.Config {
+	t := c.transport()
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	return t.TLSClientConfig
+}
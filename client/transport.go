@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// PoolConfig tunes connection pooling; zero fields keep the defaults of
+// http.DefaultTransport.
+type PoolConfig struct {
+	// MaxIdleConns caps idle connections across all hosts.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost caps idle connections kept per host (net/http
+	// defaults to 2, low for high-throughput clients of one service).
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost caps all connections per host; requests beyond it
+	// wait.
+	MaxConnsPerHost int
+	// IdleConnTimeout closes connections idle for longer.
+	IdleConnTimeout time.Duration
+	// DisableKeepAlives uses a new connection for every request.
+	DisableKeepAlives bool
+}
+
+// WithPool applies cfg to the client's Transport.
+func WithPool(cfg PoolConfig) Option {
+	return func(c *Client) {
+		t := c.transport()
+		if cfg.MaxIdleConns > 0 {
+			t.MaxIdleConns = cfg.MaxIdleConns
+		}
+		if cfg.MaxIdleConnsPerHost > 0 {
+			t.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+		}
+		if cfg.MaxConnsPerHost > 0 {
+			t.MaxConnsPerHost = cfg.MaxConnsPerHost
+		}
+		if cfg.IdleConnTimeout > 0 {
+			t.IdleConnTimeout = cfg.IdleConnTimeout
+		}
+		if cfg.DisableKeepAlives {
+			t.DisableKeepAlives = true
+		}
+	}
+}
+
+// WithHTTP1Only disables HTTP/2, for servers with broken HTTP/2 support
+// or to compare behavior.
+func WithHTTP1Only() Option {
+	return func(c *Client) {
+		t := c.transport()
+		t.ForceAttemptHTTP2 = false
+		t.Protocols = nil
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		c.tlsConfig().NextProtos = []string{"http/1.1"}
+	}
+}
+
+// WithHTTP2 negotiates HTTP/2 over TLS when the server supports it, even
+// with a custom dialer or TLS config, with which net/http otherwise sticks
+// to HTTP/1.1. For plain-text HTTP/2, see WithH2C.
+func WithHTTP2() Option {
+	return func(c *Client) {
+		t := c.transport()
+		t.ForceAttemptHTTP2 = true
+		t.Protocols = nil
+		t.TLSNextProto = nil
+	}
+}
+
+// WithH2C speaks HTTP/2 without TLS to http:// URLs, with prior knowledge
+// instead of an Upgrade, as gRPC and many internal services expect.
+// Servers must support h2c; there is no fallback to HTTP/1.1. https://
+// URLs use HTTP/2 over TLS.
+func WithH2C() Option {
+	return func(c *Client) {
+		t := c.transport()
+		var p http.Protocols
+		p.SetHTTP2(true)
+		p.SetUnencryptedHTTP2(true)
+		t.Protocols = &p
+		t.TLSNextProto = nil
+	}
+}
+
+// WithUnixSocket connects to the unix domain socket at path for every
+// request, e.g. "/var/run/docker.sock", while URLs keep their usual form
+// with any host: c.Get("http://docker/v1.43/containers/json", nil, nil).
+// Proxies are disabled.
+func WithUnixSocket(path string) Option {
+	return func(c *Client) {
+		t := c.transport()
+		t.Proxy = nil
+		var d net.Dialer
+		t.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, "unix", path)
+		}
+	}
+}
+
+// transport returns the *http.Transport under the client's interceptors
+// for options to configure, installing a clone of http.DefaultTransport
+// if none is set. It panics if the Transport is of another type.
+func (c *Client) transport() *http.Transport {
+	rt := c.Transport
+	chain, _ := rt.(*interceptorTransport)
+	if chain != nil {
+		rt = chain.base
+	}
+	if rt == nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		if chain != nil {
+			chain.base = t
+			chain.build()
+		} else {
+			c.Transport = t
+		}
+		return t
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		panic(fmt.Errorf("client: transport option needs an *http.Transport, have %T", rt))
+	}
+	return t
+}
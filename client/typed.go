@@ -0,0 +1,61 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// StatusError is returned by the typed helpers for non-2xx responses.
+type StatusError struct {
+	Response *Response
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d %s", e.Response.StatusCode, e.Response.StatusText())
+}
+
+// GetJSON sends a GET request and decodes the JSON response into a T:
+//
+//	user, resp, err := client.GetJSON[User](c, "/v1/users/7", nil, nil)
+//
+// A non-2xx response yields a *StatusError. The Response is returned
+// whenever one was received, e.g. to read headers or an error body.
+func GetJSON[T any](c *Client, baseURL string, params, headers map[string]string) (T, *Response, error) {
+	var out T
+	resp, err := c.R().SetQueryParams(params).SetHeaders(headers).Get(baseURL)
+	if err != nil {
+		return out, nil, err
+	}
+	err = decodeTyped(resp, &out)
+	return out, resp, err
+}
+
+// Do sends body encoded as JSON with method and decodes the JSON response
+// into a Resp, like GetJSON:
+//
+//	created, _, err := client.Do[NewOrder, Order](c, http.MethodPost, "/v1/orders", order, nil)
+func Do[Req, Resp any](c *Client, method, baseURL string, body Req, headers map[string]string) (Resp, *Response, error) {
+	var out Resp
+	resp, err := c.R().SetHeaders(headers).SetBodyJSON(body).Send(method, baseURL)
+	if err != nil {
+		return out, nil, err
+	}
+	err = decodeTyped(resp, &out)
+	return out, resp, err
+}
+
+// decodeTyped checks the status and decodes a non-empty body into v.
+func decodeTyped(resp *Response, v any) error {
+	if !resp.IsSuccess() {
+		resp.getDataCopy() // cache the error body and release the connection
+		return &StatusError{Response: resp}
+	}
+	if resp.StatusCode == http.StatusNoContent || resp.Request.Method == http.MethodHead {
+		return nil
+	}
+	body, err := resp.getDataCopy()
+	if err != nil || len(body) == 0 {
+		return err
+	}
+	return resp.decodeJSON(body, v)
+}
@@ -1,3 +1,3 @@
 module github.com/datanadhi/flowhttp
 
-go 1.23.12
+go 1.24
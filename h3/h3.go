@@ -0,0 +1,95 @@
+// Package h3 serves a Flow over HTTP/3 (QUIC) alongside HTTPS on TCP.
+//
+// FlowHTTP has no QUIC implementation of its own; plug one in through
+// NewServer. With quic-go:
+//
+//	newServer := func(addr string, h http.Handler, tc *tls.Config) h3.Server {
+//		return &http3.Server{Addr: addr, Handler: h, TLSConfig: http3.ConfigureTLSConfig(tc)}
+//	}
+//	root := f.Fork("/", []server.Step{h3.AltSvc(":443")})
+//	err := h3.Run(f, ":443", "cert.pem", "key.pem", newServer)
+package h3
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/datanadhi/flowhttp/server"
+)
+
+// Server is the subset of quic-go's *http3.Server that Run relies on.
+type Server interface {
+	ListenAndServe() error
+	Close() error
+}
+
+// NewServer builds the HTTP/3 server for addr, serving handler with tlsConfig.
+type NewServer func(addr string, handler http.Handler, tlsConfig *tls.Config) Server
+
+// Run serves f over HTTPS on TCP and HTTP/3 on UDP at the same address and
+// blocks until SIGINT or SIGTERM, then shuts both down; use RunContext for
+// other signals. If either server fails, the other is stopped and the error
+// returned.
+func Run(f *server.Flow, addr, certFile, keyFile string, newServer NewServer) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return RunContext(ctx, f, addr, certFile, keyFile, newServer)
+}
+
+// RunContext is like Run but shuts down when ctx is canceled instead of on
+// a signal.
+func RunContext(ctx context.Context, f *server.Flow, addr, certFile, keyFile string, newServer NewServer) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	srv := newServer(addr, f, &tls.Config{Certificates: []tls.Certificate{cert}})
+	defer srv.Close()
+
+	h3Err := make(chan error, 1)
+	go func() {
+		err := srv.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+			h3Err <- err
+			cancel()
+		}
+	}()
+
+	if err := f.RunTLSContext(ctx, addr, certFile, keyFile); err != nil {
+		return err
+	}
+	select {
+	case err := <-h3Err:
+		return fmt.Errorf("http3 error: %v", err)
+	default:
+		return nil
+	}
+}
+
+// AltSvc returns a Step advertising HTTP/3 on addr's port via the Alt-Svc
+// header, so TCP clients upgrade on their next connection. Add it to the
+// root branch.
+func AltSvc(addr string) server.Step {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		port = addr
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		panic(fmt.Errorf("h3: invalid address %q", addr))
+	}
+	value := fmt.Sprintf(`h3=":%s"; ma=86400`, port)
+	return server.CreateStep(func(next server.Sink, ctx *server.FlowContext) {
+		ctx.Response.Header().Set("Alt-Svc", value)
+		next(ctx)
+	})
+}
@@ -0,0 +1,228 @@
+// Package lambda runs a Flow on AWS Lambda behind API Gateway (REST and
+// HTTP APIs) or an Application Load Balancer, without a listening socket.
+//
+// The handler takes and returns raw JSON, so it plugs straight into
+// aws-lambda-go without this module depending on it:
+//
+//	import awslambda "github.com/aws/aws-lambda-go/lambda"
+//
+//	awslambda.Start(lambda.Handler(f))
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// HandlerFunc is a Lambda handler taking and returning raw event JSON.
+type HandlerFunc func(ctx context.Context, event json.RawMessage) (json.RawMessage, error)
+
+// event covers the fields used from API Gateway REST (payload v1), HTTP API
+// (payload v2) and ALB events.
+type event struct {
+	Version                         string              `json:"version"`
+	HTTPMethod                      string              `json:"httpMethod"`
+	Path                            string              `json:"path"`
+	RawPath                         string              `json:"rawPath"`
+	RawQueryString                  string              `json:"rawQueryString"`
+	Cookies                         []string            `json:"cookies"`
+	Headers                         map[string]string   `json:"headers"`
+	MultiValueHeaders               map[string][]string `json:"multiValueHeaders"`
+	QueryStringParameters           map[string]string   `json:"queryStringParameters"`
+	MultiValueQueryStringParameters map[string][]string `json:"multiValueQueryStringParameters"`
+	Body                            string              `json:"body"`
+	IsBase64Encoded                 bool                `json:"isBase64Encoded"`
+	RequestContext                  struct {
+		DomainName string `json:"domainName"`
+		HTTP       struct {
+			Method   string `json:"method"`
+			SourceIP string `json:"sourceIp"`
+		} `json:"http"`
+		Identity struct {
+			SourceIP string `json:"sourceIp"`
+		} `json:"identity"`
+		ELB *struct {
+			TargetGroupArn string `json:"targetGroupArn"`
+		} `json:"elb"`
+	} `json:"requestContext"`
+}
+
+// response is the union of the API Gateway and ALB response shapes.
+type response struct {
+	StatusCode        int                 `json:"statusCode"`
+	StatusDescription string              `json:"statusDescription,omitempty"`
+	Headers           map[string]string   `json:"headers,omitempty"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders,omitempty"`
+	Cookies           []string            `json:"cookies,omitempty"`
+	Body              string              `json:"body"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded"`
+}
+
+// Handler returns a Lambda handler that converts each event into an
+// *http.Request, serves it with h (typically a Flow) and converts the
+// recorded response back into the matching response shape.
+func Handler(h http.Handler) HandlerFunc {
+	return func(ctx context.Context, raw json.RawMessage) (json.RawMessage, error) {
+		var ev event
+		if err := json.Unmarshal(raw, &ev); err != nil {
+			return nil, fmt.Errorf("lambda: decode event: %v", err)
+		}
+		req, err := ev.request(ctx)
+		if err != nil {
+			return nil, err
+		}
+		w := &responseWriter{header: make(http.Header)}
+		h.ServeHTTP(w, req)
+		return json.Marshal(ev.response(w))
+	}
+}
+
+func (ev *event) request(ctx context.Context) (*http.Request, error) {
+	v2 := ev.Version == "2.0"
+	method, path, remote := ev.HTTPMethod, ev.Path, ev.RequestContext.Identity.SourceIP
+	if v2 {
+		method, path, remote = ev.RequestContext.HTTP.Method, ev.RawPath, ev.RequestContext.HTTP.SourceIP
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	var query string
+	switch {
+	case v2:
+		query = ev.RawQueryString
+	case ev.MultiValueQueryStringParameters != nil:
+		query = encodeQuery(ev.MultiValueQueryStringParameters, ev.RequestContext.ELB != nil)
+	case ev.QueryStringParameters != nil:
+		q := make(map[string][]string, len(ev.QueryStringParameters))
+		for k, v := range ev.QueryStringParameters {
+			q[k] = []string{v}
+		}
+		query = encodeQuery(q, ev.RequestContext.ELB != nil)
+	}
+
+	body := []byte(ev.Body)
+	if ev.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(ev.Body)
+		if err != nil {
+			return nil, fmt.Errorf("lambda: decode body: %v", err)
+		}
+		body = decoded
+	}
+
+	u := &url.URL{Path: path, RawQuery: query}
+	if v2 {
+		// rawPath is still percent-encoded; keep it as sent so it isn't
+		// escaped twice.
+		decoded, err := url.PathUnescape(path)
+		if err != nil {
+			return nil, fmt.Errorf("lambda: decode path: %v", err)
+		}
+		u.Path, u.RawPath = decoded, path
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("lambda: build request: %v", err)
+	}
+	for k, v := range ev.Headers {
+		req.Header.Set(k, v)
+	}
+	for k, vs := range ev.MultiValueHeaders {
+		req.Header.Del(k)
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if len(ev.Cookies) > 0 {
+		req.Header.Set("Cookie", strings.Join(ev.Cookies, "; "))
+	}
+	req.Host = req.Header.Get("Host")
+	if req.Host == "" {
+		req.Host = ev.RequestContext.DomainName
+	}
+	req.URL.Host = req.Host
+	req.RemoteAddr = remote
+	req.RequestURI = u.RequestURI()
+	return req, nil
+}
+
+// encodeQuery rebuilds a query string. ALB passes parameters through still
+// URL-encoded, while API Gateway decodes them.
+func encodeQuery(params map[string][]string, encoded bool) string {
+	if !encoded {
+		return url.Values(params).Encode()
+	}
+	var parts []string
+	for k, vs := range params {
+		for _, v := range vs {
+			parts = append(parts, k+"="+v)
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func (ev *event) response(w *responseWriter) response {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	resp := response{StatusCode: status}
+	if ev.RequestContext.ELB != nil {
+		resp.StatusDescription = fmt.Sprintf("%d %s", status, http.StatusText(status))
+	}
+
+	body := w.body.Bytes()
+	if w.header.Get("Content-Encoding") != "" || !utf8.Valid(body) {
+		resp.Body, resp.IsBase64Encoded = base64.StdEncoding.EncodeToString(body), true
+	} else {
+		resp.Body = string(body)
+	}
+
+	switch {
+	case ev.Version == "2.0":
+		// HTTP APIs take Set-Cookie separately and comma-join other repeats.
+		resp.Cookies = w.header.Values("Set-Cookie")
+		resp.Headers = make(map[string]string, len(w.header))
+		for k, vs := range w.header {
+			if k != "Set-Cookie" {
+				resp.Headers[k] = strings.Join(vs, ",")
+			}
+		}
+	case ev.RequestContext.ELB != nil && ev.MultiValueHeaders == nil:
+		// ALB without multi-value headers enabled rejects multiValueHeaders.
+		resp.Headers = make(map[string]string, len(w.header))
+		for k, vs := range w.header {
+			resp.Headers[k] = vs[len(vs)-1]
+		}
+	default:
+		resp.MultiValueHeaders = w.header
+	}
+	return resp
+}
+
+// responseWriter records the response for conversion back into an event.
+type responseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseWriter) Header() http.Header { return w.header }
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
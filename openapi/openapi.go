@@ -0,0 +1,276 @@
+// Package openapi generates an OpenAPI 3 document from a Flow's routes.
+//
+// Routes are documented by attaching options to the *server.Route that
+// Stream returns; request and response types are described by generics:
+//
+//	openapi.Doc(f.Stream("POST", "/users", nil, createUser),
+//		openapi.Summary("Create a user"),
+//		openapi.Tags("users"),
+//		openapi.Body[CreateUser](),
+//		openapi.Response[User](201),
+//	)
+//	openapi.Mount(f, "/openapi.json", openapi.Info{Title: "Users API", Version: "1.0.0"})
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/datanadhi/flowhttp/server"
+)
+
+const (
+	// MetaKey is the route metadata key holding a route's *Operation.
+	MetaKey = "openapi.operation"
+	// SpecMetaKey marks the route serving the document; Branch.ServeDocs
+	// looks for it to find the spec URL.
+	SpecMetaKey = server.SpecMetaKey
+	// RequestTypeMetaKey and ResponseTypeMetaKey hold the reflect.Type of
+	// server.Handle's Req and Resp, used unless Body or Response say otherwise.
+	RequestTypeMetaKey  = server.RequestTypeMetaKey
+	ResponseTypeMetaKey = server.ResponseTypeMetaKey
+	// DocsMetaKey marks docs pages served by Branch.ServeDocs, which are
+	// left out of the document.
+	DocsMetaKey = server.DocsMetaKey
+)
+
+// Document is an OpenAPI 3 document.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info is the document's metadata.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Server is a base URL the API is served from.
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower-case methods to operations.
+type PathItem map[string]*Operation
+
+// Components holds reusable schemas, referenced as #/components/schemas/Name.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Operation documents one route.
+type Operation struct {
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	OperationID string                     `json:"operationId,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Deprecated  bool                       `json:"deprecated,omitempty"`
+	Parameters  []Parameter                `json:"parameters,omitempty"`
+	RequestBody *RequestBody               `json:"requestBody,omitempty"`
+	Responses   map[string]*ResponseObject `json:"responses"`
+
+	hidden    bool
+	body      reflect.Type
+	responses map[int]reflect.Type
+}
+
+// Parameter is a path, query or header parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes the accepted body.
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// ResponseObject describes one status code's response.
+type ResponseObject struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType pairs a content type with its schema.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Option documents a route; see Doc.
+type Option func(*Operation)
+
+// Doc applies opts to the route's operation and returns the route.
+func Doc(r *server.Route, opts ...Option) *server.Route {
+	op, _ := r.Meta(MetaKey).(*Operation)
+	if op == nil {
+		op = &Operation{}
+		r.SetMeta(MetaKey, op)
+	}
+	for _, opt := range opts {
+		opt(op)
+	}
+	return r
+}
+
+// Summary sets the one-line summary.
+func Summary(s string) Option { return func(o *Operation) { o.Summary = s } }
+
+// Description sets the long description (Markdown allowed).
+func Description(s string) Option { return func(o *Operation) { o.Description = s } }
+
+// OperationID sets the unique operation id used by client generators.
+func OperationID(id string) Option { return func(o *Operation) { o.OperationID = id } }
+
+// Tags groups the operation.
+func Tags(tags ...string) Option {
+	return func(o *Operation) { o.Tags = append(o.Tags, tags...) }
+}
+
+// Deprecated marks the operation deprecated.
+func Deprecated() Option { return func(o *Operation) { o.Deprecated = true } }
+
+// Hidden leaves the route out of the document.
+func Hidden() Option { return func(o *Operation) { o.hidden = true } }
+
+// Query documents a string query parameter.
+func Query(name, description string, required bool) Option {
+	return param("query", name, description, required)
+}
+
+// Header documents a request header.
+func Header(name, description string, required bool) Option {
+	return param("header", name, description, required)
+}
+
+func param(in, name, description string, required bool) Option {
+	return func(o *Operation) {
+		o.Parameters = append(o.Parameters, Parameter{
+			Name: name, In: in, Description: description, Required: required,
+			Schema: &Schema{Type: "string"},
+		})
+	}
+}
+
+// Body documents a JSON request body of type T.
+func Body[T any]() Option {
+	return func(o *Operation) { o.body = reflect.TypeFor[T]() }
+}
+
+// Response documents a JSON response of type T for status.
+func Response[T any](status int) Option {
+	return func(o *Operation) {
+		if o.responses == nil {
+			o.responses = make(map[int]reflect.Type)
+		}
+		o.responses[status] = reflect.TypeFor[T]()
+	}
+}
+
+// NoContent documents an empty response for status.
+func NoContent(status int) Option {
+	return func(o *Operation) {
+		if o.responses == nil {
+			o.responses = make(map[int]reflect.Type)
+		}
+		o.responses[status] = nil
+	}
+}
+
+var pathParam = regexp.MustCompile(`:([a-zA-Z0-9_]+)`)
+
+// Generate builds the document for every route registered on f. Routes
+// without Doc options are included with path parameters and a generic
+// 200 response.
+func Generate(f *server.Flow, info Info) *Document {
+	doc := &Document{OpenAPI: "3.0.3", Info: info, Paths: make(map[string]PathItem)}
+	g := newGenerator()
+	for _, r := range f.Routes() {
+		tmpl, _ := r.Meta(MetaKey).(*Operation)
+		if tmpl == nil {
+			tmpl = &Operation{}
+		}
+		if tmpl.hidden || r.Meta(DocsMetaKey) == true {
+			continue
+		}
+		op := *tmpl
+		op.Parameters = nil
+
+		path := pathParam.ReplaceAllString(r.Pattern, "{$1}")
+		for _, m := range pathParam.FindAllStringSubmatch(r.Pattern, -1) {
+			op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+		if strings.Contains(path, "*") {
+			path = strings.Replace(path, "*", "{path}", 1)
+			op.Parameters = append(op.Parameters, Parameter{Name: "path", In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+		op.Parameters = append(op.Parameters, tmpl.Parameters...)
+
+		body, responses := tmpl.body, tmpl.responses
+		if req, ok := r.Meta(RequestTypeMetaKey).(reflect.Type); ok {
+			op.Parameters = append(op.Parameters, queryParams(req)...)
+			if body == nil && r.Method != http.MethodGet && hasBodyFields(req) {
+				body = req
+			}
+		}
+		if resp, ok := r.Meta(ResponseTypeMetaKey).(reflect.Type); ok && responses == nil {
+			responses = map[int]reflect.Type{http.StatusOK: resp}
+		}
+
+		if body != nil {
+			op.RequestBody = &RequestBody{Required: true, Content: jsonContent(g.schema(body))}
+		}
+		op.Responses = make(map[string]*ResponseObject)
+		for status, t := range responses {
+			resp := &ResponseObject{Description: http.StatusText(status)}
+			if t != nil {
+				resp.Content = jsonContent(g.schema(t))
+			}
+			op.Responses[strconv.Itoa(status)] = resp
+		}
+		if len(op.Responses) == 0 {
+			op.Responses["200"] = &ResponseObject{Description: "OK"}
+		}
+
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(r.Method)] = &op
+	}
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+func jsonContent(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
+
+// Mount serves the document generated from f at path. It is generated on
+// the first request, so routes registered after Mount are included.
+func Mount(f *server.Flow, path string, info Info, steps ...server.Step) *server.Route {
+	var (
+		once sync.Once
+		doc  *Document
+	)
+	r := f.Stream("GET", path, steps, func(ctx *server.FlowContext) {
+		once.Do(func() { doc = Generate(f, info) })
+		ctx.JSON(http.StatusOK, doc)
+	})
+	r.SetMeta(SpecMetaKey, true)
+	return Doc(r, Hidden())
+}
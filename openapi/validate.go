@@ -0,0 +1,301 @@
+package openapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datanadhi/flowhttp/server"
+)
+
+// Violation is one way a request or response breaks the spec.
+type Violation struct {
+	// Location is where the problem is, e.g. "query.limit" or "body.items[2].name".
+	Location string `json:"location"`
+	Message  string `json:"message"`
+}
+
+// ValidateConfig configures Validate.
+type ValidateConfig struct {
+	// Document is the spec to validate against. When nil, it is generated
+	// from the Flow passed to Validate on first use.
+	Document *Document
+	// Responses also validates JSON response bodies, replacing invalid ones
+	// with a 500 report. Meant for development and CI.
+	Responses bool
+}
+
+// Validate returns a Step checking requests against the operation of the
+// matched route: required and typed query/header parameters and the JSON
+// body. Violations are answered with a 400 *server.HTTPError whose Details
+// lists them. Routes missing from the document pass through.
+func Validate(f *server.Flow, cfg ValidateConfig) server.Step {
+	var once sync.Once
+	doc := cfg.Document
+	resolve := func() *Document {
+		once.Do(func() {
+			if doc == nil {
+				doc = Generate(f, Info{})
+			}
+		})
+		return doc
+	}
+	return func(next server.Sink) server.Sink {
+		check := func(ctx *server.FlowContext) {
+			op := resolve().operation(ctx)
+			if op == nil {
+				next(ctx)
+				return
+			}
+			v := &validator{doc: doc}
+			v.request(ctx, op)
+			if len(v.violations) > 0 {
+				ctx.Error(&server.HTTPError{Code: http.StatusBadRequest, Message: "request validation failed", Details: v.violations})
+				return
+			}
+			next(ctx)
+			if cfg.Responses {
+				v.response(ctx, op)
+			}
+		}
+		if cfg.Responses {
+			return server.BufferResponse(0)(check)
+		}
+		return check
+	}
+}
+
+// operation finds the documented operation for the matched route.
+func (d *Document) operation(ctx *server.FlowContext) *Operation {
+	r := ctx.Route()
+	if r == nil {
+		return nil
+	}
+	path := pathParam.ReplaceAllString(r.Pattern, "{$1}")
+	path = strings.Replace(path, "*", "{path}", 1)
+	return d.Paths[path][strings.ToLower(r.Method)]
+}
+
+type validator struct {
+	doc        *Document
+	violations []Violation
+}
+
+func (v *validator) add(location, format string, args ...any) {
+	v.violations = append(v.violations, Violation{Location: location, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) request(ctx *server.FlowContext, op *Operation) {
+	query := ctx.Request.URL.Query()
+	for _, p := range op.Parameters {
+		var values []string
+		switch p.In {
+		case "query":
+			values = query[p.Name]
+		case "header":
+			values = ctx.Request.Header.Values(p.Name)
+		case "path":
+			if val := ctx.Param(p.Name); val != "" {
+				values = []string{val}
+			}
+		default:
+			continue
+		}
+		loc := p.In + "." + p.Name
+		if len(values) == 0 {
+			if p.Required && p.In != "path" {
+				v.add(loc, "is required")
+			}
+			continue
+		}
+		v.param(loc, p.Schema, values)
+	}
+
+	if op.RequestBody == nil {
+		return
+	}
+	body, err := ctx.RawBody()
+	if err != nil {
+		v.add("body", "%v", err)
+		return
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		if op.RequestBody.Required {
+			v.add("body", "is required")
+		}
+		return
+	}
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok {
+		return
+	}
+	if ct := ctx.Request.Header.Get("Content-Type"); ct != "" && !strings.Contains(ct, "json") {
+		v.add("body", "content type %q is not application/json", ct)
+		return
+	}
+	var data any
+	if err := json.Unmarshal(body, &data); err != nil {
+		v.add("body", "invalid JSON: %v", err)
+		return
+	}
+	v.value("body", media.Schema, data)
+}
+
+// param checks string parameter values against a scalar or array schema.
+func (v *validator) param(loc string, s *Schema, values []string) {
+	s = v.deref(s)
+	if s == nil {
+		return
+	}
+	if s.Type == "array" {
+		for i, val := range values {
+			v.param(fmt.Sprintf("%s[%d]", loc, i), s.Items, []string{val})
+		}
+		return
+	}
+	val := values[0]
+	switch s.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(val, 10, 64); err != nil {
+			v.add(loc, "must be an integer")
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(val, 64); err != nil {
+			v.add(loc, "must be a number")
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(val); err != nil {
+			v.add(loc, "must be a boolean")
+		}
+	case "string":
+		v.format(loc, s.Format, val)
+	}
+}
+
+// value checks decoded JSON against s.
+func (v *validator) value(loc string, s *Schema, data any) {
+	s = v.deref(s)
+	if s == nil || s.Type == "" {
+		return
+	}
+	if data == nil {
+		if !s.Nullable {
+			v.add(loc, "must not be null")
+		}
+		return
+	}
+	switch s.Type {
+	case "object":
+		obj, ok := data.(map[string]any)
+		if !ok {
+			v.add(loc, "must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				v.add(loc+"."+name, "is required")
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if prop, ok := s.Properties[k]; ok {
+				v.value(loc+"."+k, prop, obj[k])
+			} else if s.AdditionalProperties != nil {
+				v.value(loc+"."+k, s.AdditionalProperties, obj[k])
+			}
+		}
+	case "array":
+		arr, ok := data.([]any)
+		if !ok {
+			v.add(loc, "must be an array")
+			return
+		}
+		for i, item := range arr {
+			v.value(fmt.Sprintf("%s[%d]", loc, i), s.Items, item)
+		}
+	case "string":
+		str, ok := data.(string)
+		if !ok {
+			v.add(loc, "must be a string")
+			return
+		}
+		v.format(loc, s.Format, str)
+	case "integer":
+		n, ok := data.(float64)
+		if !ok || n != math.Trunc(n) {
+			v.add(loc, "must be an integer")
+		}
+	case "number":
+		if _, ok := data.(float64); !ok {
+			v.add(loc, "must be a number")
+		}
+	case "boolean":
+		if _, ok := data.(bool); !ok {
+			v.add(loc, "must be a boolean")
+		}
+	}
+}
+
+func (v *validator) format(loc, format, val string) {
+	switch format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, val); err != nil {
+			v.add(loc, "must be an RFC 3339 date-time")
+		}
+	case "byte":
+		if _, err := base64.StdEncoding.DecodeString(val); err != nil {
+			v.add(loc, "must be base64")
+		}
+	}
+}
+
+// deref resolves component references.
+func (v *validator) deref(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = v.doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// response validates a buffered JSON response against the documented one
+// for its status, replacing it with a 500 report on violations.
+func (v *validator) response(ctx *server.FlowContext, op *Operation) {
+	buf := ctx.ResponseBuffer()
+	if buf == nil || buf.Streamed() {
+		return
+	}
+	status := buf.Status()
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		resp, ok = op.Responses["default"]
+	}
+	if !ok {
+		if status < 400 {
+			v.add("response.status", "status %d is not documented", status)
+		}
+	} else if media, ok := resp.Content["application/json"]; ok {
+		var data any
+		if err := json.Unmarshal(buf.Body(), &data); err != nil {
+			v.add("response.body", "invalid JSON: %v", err)
+		} else {
+			v.value("response.body", media.Schema, data)
+		}
+	}
+	if len(v.violations) == 0 {
+		return
+	}
+	report, _ := json.Marshal(map[string]any{"error": "response validation failed", "details": v.violations})
+	buf.Header().Set("Content-Type", "application/json")
+	buf.Rewrite(http.StatusInternalServerError, append(report, '\n'))
+}
@@ -0,0 +1,71 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+)
+
+// Inject adds the current span's traceparent/tracestate to headers, so calls
+// made with the client package join the server request's trace:
+//
+//	resp, err := c.Get(url, nil, otel.Inject(ctx.Request.Context(), nil))
+//
+// A nil headers map is allocated.
+func Inject(ctx context.Context, headers map[string]string) map[string]string {
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	if span := SpanFromContext(ctx); span != nil {
+		headers["traceparent"] = span.Context.Traceparent()
+		if ts := span.Context.TraceState; ts != "" {
+			headers["tracestate"] = ts
+		}
+	}
+	return headers
+}
+
+// Transport wraps base (http.DefaultTransport if nil) so every outgoing
+// request gets a client span, child of the span in the request's context,
+// and carries the traceparent header. Assign it to client.Client.Transport.
+func Transport(base http.RoundTripper, exp Exporter) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, exp: exp}
+}
+
+type transport struct {
+	base http.RoundTripper
+	exp  Exporter
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var parent SpanContext
+	if p := SpanFromContext(req.Context()); p != nil {
+		parent = p.Context
+	}
+	span := StartSpan(req.Method, SpanKindClient, parent, t.exp)
+	span.SetAttribute("http.request.method", req.Method)
+	span.SetAttribute("url.full", req.URL.String())
+	span.SetAttribute("server.address", req.URL.Hostname())
+
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(ContextWithSpan(req.Context(), span))
+	req.Header.Set("traceparent", span.Context.Traceparent())
+	if ts := span.Context.TraceState; ts != "" {
+		req.Header.Set("tracestate", ts)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+	span.SetAttribute("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode >= 400 {
+		span.SetStatus(StatusError, resp.Status)
+	}
+	span.End()
+	return resp, nil
+}
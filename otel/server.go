@@ -0,0 +1,88 @@
+package otel
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/datanadhi/flowhttp/server"
+)
+
+// Tracing returns a Step that starts a server span per request. The span is
+// named "METHOD /route/:template", continues any incoming traceparent, and
+// is stored in the request context for handlers and outgoing calls.
+// 5xx responses and panics mark the span as failed.
+func Tracing(exp Exporter) server.Step {
+	return server.CreateStep(func(next server.Sink, ctx *server.FlowContext) {
+		r := ctx.Request
+		parent, _ := ParseTraceparent(r.Header.Get("traceparent"))
+		if parent.IsValid() {
+			parent.TraceState = r.Header.Get("tracestate")
+		}
+
+		name, route := r.Method+" "+r.URL.Path, ""
+		if rt := ctx.Route(); rt != nil {
+			name, route = rt.Method+" "+rt.Pattern, rt.Pattern
+		}
+		span := StartSpan(name, SpanKindServer, parent, exp)
+		span.SetAttribute("http.request.method", r.Method)
+		span.SetAttribute("url.path", r.URL.Path)
+		span.SetAttribute("server.address", r.Host)
+		if route != "" {
+			span.SetAttribute("http.route", route)
+		}
+		if ua := r.UserAgent(); ua != "" {
+			span.SetAttribute("user_agent.original", ua)
+		}
+
+		sw := &statusWriter{ResponseWriter: ctx.Response}
+		orig := ctx.Response
+		ctx.Response = sw
+		ctx.Request = r.WithContext(ContextWithSpan(r.Context(), span))
+		defer func() {
+			ctx.Response = orig
+			if p := recover(); p != nil {
+				span.RecordError(fmt.Errorf("panic: %v", p))
+				span.End()
+				panic(p)
+			}
+			status := sw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttribute("http.response.status_code", status)
+			if status >= 500 {
+				span.SetStatus(StatusError, http.StatusText(status))
+			}
+			span.End()
+		}()
+		next(ctx)
+	})
+}
+
+// statusWriter records the response status for the span.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if fl, ok := w.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
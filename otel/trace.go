@@ -0,0 +1,178 @@
+// Package otel adds distributed tracing to FlowHTTP without pulling in the
+// OpenTelemetry SDK. It speaks W3C Trace Context on the wire and hands
+// finished spans to an Exporter, which can bridge to any tracing backend.
+package otel
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanKind mirrors the OpenTelemetry span kinds used here.
+type SpanKind int
+
+const (
+	SpanKindServer SpanKind = iota + 1
+	SpanKindClient
+)
+
+// StatusCode mirrors the OpenTelemetry span status codes.
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = iota
+	StatusOK
+	StatusError
+)
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	Flags      byte
+	TraceState string
+}
+
+// IsValid reports whether both IDs are non-zero.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Sampled reports whether the sampled flag is set.
+func (sc SpanContext) Sampled() bool { return sc.Flags&0x01 != 0 }
+
+// Traceparent formats the span context as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]), sc.Flags)
+}
+
+// ParseTraceparent parses a W3C traceparent header value.
+func ParseTraceparent(v string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return sc, false
+	}
+	sc.Flags = flags[0]
+	return sc, sc.IsValid()
+}
+
+// Span is a single timed operation. It is safe for concurrent use.
+type Span struct {
+	Name       string
+	Kind       SpanKind
+	Context    SpanContext
+	Parent     SpanContext
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes map[string]any
+	Status     StatusCode
+	StatusMsg  string
+	Errors     []error
+
+	mu       sync.Mutex
+	exporter Exporter
+	ended    bool
+}
+
+// SetAttribute records a key/value attribute.
+func (s *Span) SetAttribute(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Attributes[key] = value
+}
+
+// RecordError attaches err and marks the span as failed.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Errors = append(s.Errors, err)
+	s.Status, s.StatusMsg = StatusError, err.Error()
+}
+
+// SetStatus sets the span status.
+func (s *Span) SetStatus(code StatusCode, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Status, s.StatusMsg = code, msg
+}
+
+// End finishes the span and exports it (once).
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	s.mu.Unlock()
+	if s.exporter != nil && s.Context.Sampled() {
+		s.exporter.ExportSpan(s)
+	}
+}
+
+// Exporter receives finished, sampled spans.
+type Exporter interface {
+	ExportSpan(s *Span)
+}
+
+// ExporterFunc adapts a function to Exporter.
+type ExporterFunc func(s *Span)
+
+func (fn ExporterFunc) ExportSpan(s *Span) { fn(s) }
+
+type spanKey struct{}
+
+// ContextWithSpan returns ctx carrying span as the current span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the current span (nil if none).
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// StartSpan starts a span that is a child of parent when parent is valid,
+// or the root of a new, sampled trace otherwise.
+func StartSpan(name string, kind SpanKind, parent SpanContext, exp Exporter) *Span {
+	sc := SpanContext{Flags: 0x01}
+	if parent.IsValid() {
+		sc.TraceID, sc.Flags, sc.TraceState = parent.TraceID, parent.Flags, parent.TraceState
+	} else {
+		rand.Read(sc.TraceID[:])
+	}
+	rand.Read(sc.SpanID[:])
+	return &Span{
+		Name:       name,
+		Kind:       kind,
+		Context:    sc,
+		Parent:     parent,
+		StartTime:  time.Now(),
+		Attributes: make(map[string]any),
+		exporter:   exp,
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// AccessLogConfig configures AccessLog.
+type AccessLogConfig struct {
+	// Output receives one line per request (defaults to os.Stdout); use a
+	// RotatingFile to log to disk.
+	Output io.Writer
+	// JSON writes JSON lines instead of the Apache combined log format.
+	JSON bool
+}
+
+// accessEntry is a JSON access log line.
+type accessEntry struct {
+	Time       time.Time `json:"time"`
+	ClientIP   string    `json:"client_ip"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Route      string    `json:"route,omitempty"`
+	Proto      string    `json:"proto"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	DurationMs float64   `json:"duration_ms"`
+	Referer    string    `json:"referer,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+// AccessLog returns a Step that logs every request once it completes.
+func AccessLog(cfg AccessLogConfig) Step {
+	out := cfg.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	var mu sync.Mutex
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: ctx.Response}
+		ctx.Response = sw
+		defer func() {
+			ctx.Response = sw.ResponseWriter
+			status := sw.status
+			if status == 0 {
+				status = 200
+			}
+			r := ctx.Request
+			var line []byte
+			if cfg.JSON {
+				e := accessEntry{
+					Time: start, ClientIP: ctx.ClientIP(), Method: r.Method, Path: r.URL.RequestURI(),
+					Proto: r.Proto, Status: status, Bytes: sw.size,
+					DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+					Referer:    r.Referer(), UserAgent: r.UserAgent(),
+				}
+				if rt := ctx.Route(); rt != nil {
+					e.Route = rt.Pattern
+				}
+				line, _ = json.Marshal(e)
+				line = append(line, '\n')
+			} else {
+				line = fmt.Appendf(nil, "%s - - [%s] %q %d %d %q %q\n",
+					ctx.ClientIP(), start.Format("02/Jan/2006:15:04:05 -0700"),
+					r.Method+" "+r.URL.RequestURI()+" "+r.Proto, status, sw.size,
+					r.Referer(), r.UserAgent())
+			}
+			mu.Lock()
+			out.Write(line)
+			mu.Unlock()
+		}()
+		next(ctx)
+	})
+}
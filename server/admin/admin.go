@@ -0,0 +1,262 @@
+// Package admin serves a small operational dashboard for a Flow: its
+// routes and their Steps, recent requests, per-route error rates and the
+// registered modules. It is a server.Module:
+//
+//	f.Use(admin.New(admin.Config{Steps: []server.Step{auth}}))
+package admin
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datanadhi/flowhttp/server"
+)
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// Config configures the dashboard.
+type Config struct {
+	// Path the dashboard is mounted at (defaults to "/admin"); its data is
+	// served as JSON from Path+"/api".
+	Path string
+	// Steps guard the dashboard, e.g. an APIKey or JWT Step. They are
+	// required: Register fails without them.
+	Steps []server.Step
+	// Recent is how many requests are kept (defaults to 100).
+	Recent int
+}
+
+// Request is a recently served request. Path leaves out the query string,
+// which may carry tokens, and Method is "OTHER" for non-standard methods.
+type Request struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Route      string    `json:"route,omitempty"`
+	Status     int       `json:"status"`
+	DurationMs float64   `json:"duration_ms"`
+}
+
+// Stats counts requests and errors, for a route or overall.
+type Stats struct {
+	Requests     int64   `json:"requests"`
+	ClientErrors int64   `json:"client_errors"`
+	ServerErrors int64   `json:"server_errors"`
+	ErrorRate    float64 `json:"error_rate"` // share of 5xx responses
+}
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method  string   `json:"method"`
+	Pattern string   `json:"pattern"`
+	Steps   []string `json:"steps"`
+	Skipped []string `json:"skipped,omitempty"`
+	Stats   Stats    `json:"stats"`
+}
+
+// Snapshot is the dashboard's data.
+type Snapshot struct {
+	Started time.Time   `json:"started"`
+	Uptime  string      `json:"uptime"`
+	Totals  Stats       `json:"totals"`
+	Routes  []RouteInfo `json:"routes"`
+	Recent  []Request   `json:"recent"`
+	Modules []string    `json:"modules"`
+}
+
+// Dashboard records requests and serves the dashboard.
+type Dashboard struct {
+	cfg     Config
+	flow    *server.Flow
+	started time.Time
+
+	mu     sync.Mutex
+	recent []Request // ring buffer, next is the oldest once full
+	next   int
+	totals Stats
+	routes map[string]*Stats // by "METHOD pattern" of registered routes
+}
+
+// New creates a Dashboard; register it with f.Use.
+func New(cfg Config) *Dashboard {
+	if cfg.Path == "" {
+		cfg.Path = "/admin"
+	}
+	cfg.Path = "/" + strings.Trim(cfg.Path, "/")
+	if cfg.Recent <= 0 {
+		cfg.Recent = 100
+	}
+	return &Dashboard{cfg: cfg, routes: map[string]*Stats{}}
+}
+
+// Register mounts the dashboard on f and starts recording requests.
+func (d *Dashboard) Register(f *server.Flow) error {
+	if len(d.cfg.Steps) == 0 {
+		return errors.New("admin: Config.Steps must guard the dashboard")
+	}
+	if d.flow != nil {
+		return errors.New("admin: dashboard already registered")
+	}
+	d.flow = f
+	d.started = time.Now()
+	f.PreRouting(server.CreateStep(d.record))
+	f.Stream("GET", d.cfg.Path, d.cfg.Steps, func(ctx *server.FlowContext) {
+		ctx.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+		ctx.Response.Header().Set("Cache-Control", "no-store")
+		ctx.Response.Write(dashboardHTML)
+	})
+	f.Stream("GET", d.cfg.Path+"/api", d.cfg.Steps, func(ctx *server.FlowContext) {
+		ctx.Response.Header().Set("Cache-Control", "no-store")
+		ctx.JSON(http.StatusOK, d.Snapshot())
+	})
+	return nil
+}
+
+// record is a pre-routing Step timing every request but the dashboard's.
+func (d *Dashboard) record(next server.Sink, ctx *server.FlowContext) {
+	start := time.Now()
+	sw := &statusWriter{ResponseWriter: ctx.Response}
+	ctx.Response = sw
+	defer func() {
+		ctx.Response = sw.ResponseWriter
+		// a panic escaping the chain still counts, as the 500 the server
+		// answers with, before it carries on up the stack
+		p := recover()
+		if p != nil {
+			defer panic(p)
+		}
+		route := ctx.Route()
+		pattern := ""
+		if route != nil {
+			if route.Pattern == d.cfg.Path || route.Pattern == d.cfg.Path+"/api" {
+				return
+			}
+			pattern = route.Pattern
+		}
+		status := sw.status
+		if p != nil {
+			status = http.StatusInternalServerError
+		} else if status == 0 {
+			status = http.StatusOK
+		}
+		d.add(Request{
+			Time: start, Method: method(ctx.Request.Method), Path: ctx.Request.URL.EscapedPath(), Route: pattern, Status: status,
+			DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+		}, route)
+	}()
+	next(ctx)
+}
+
+// method returns m if it is a standard method and "OTHER" otherwise, so
+// clients can't fill the dashboard with made-up methods.
+func method(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return m
+	}
+	return "OTHER"
+}
+
+// add records req; its per-route stats go to route, while unmatched
+// requests only count towards the totals.
+func (d *Dashboard) add(req Request, route *server.Route) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.recent) < d.cfg.Recent {
+		d.recent = append(d.recent, req)
+	} else {
+		d.recent[d.next] = req
+		d.next = (d.next + 1) % len(d.recent)
+	}
+	d.totals.count(req.Status)
+	if route == nil {
+		return
+	}
+	key := route.Method + " " + route.Pattern
+	s := d.routes[key]
+	if s == nil {
+		s = &Stats{}
+		d.routes[key] = s
+	}
+	s.count(req.Status)
+}
+
+func (s *Stats) count(status int) {
+	s.Requests++
+	switch {
+	case status >= 500:
+		s.ServerErrors++
+	case status >= 400:
+		s.ClientErrors++
+	}
+	s.ErrorRate = float64(s.ServerErrors) / float64(s.Requests)
+}
+
+// Snapshot returns the current dashboard data, newest requests first.
+func (d *Dashboard) Snapshot() Snapshot {
+	snap := Snapshot{Started: d.started, Uptime: time.Since(d.started).Round(time.Second).String()}
+	if d.flow == nil {
+		return snap
+	}
+	for _, m := range d.flow.Modules() {
+		snap.Modules = append(snap.Modules, fmt.Sprintf("%T", m))
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	snap.Totals = d.totals
+	for _, r := range d.flow.Routes() {
+		info := RouteInfo{Method: r.Method, Pattern: r.Pattern, Steps: r.Steps()}
+		for _, name := range info.Steps {
+			if r.Skips(name) {
+				info.Skipped = append(info.Skipped, name)
+			}
+		}
+		if s := d.routes[r.Method+" "+r.Pattern]; s != nil {
+			info.Stats = *s
+		}
+		snap.Routes = append(snap.Routes, info)
+	}
+	sort.SliceStable(snap.Routes, func(a, b int) bool { return snap.Routes[a].Pattern < snap.Routes[b].Pattern })
+	snap.Recent = make([]Request, 0, len(d.recent))
+	for i := range d.recent {
+		snap.Recent = append(snap.Recent, d.recent[(d.next-1-i+2*len(d.recent))%len(d.recent)])
+	}
+	return snap
+}
+
+// statusWriter captures the response status.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if fl, ok := w.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+)
+
+// APIKeyInfoKey is the ctx key under which the APIKey Step stores key metadata.
+const APIKeyInfoKey = "apikey.info"
+
+// APIKeyInfo is the metadata attached to a valid API key.
+type APIKeyInfo struct {
+	Owner  string
+	Scopes []string
+	Meta   map[string]any
+}
+
+// KeyValidator resolves an API key to its metadata.
+// Returning a nil info or an error rejects the request.
+type KeyValidator interface {
+	ValidateKey(ctx context.Context, key string) (*APIKeyInfo, error)
+}
+
+// KeyValidatorFunc adapts a function (e.g. a DB lookup) to KeyValidator.
+type KeyValidatorFunc func(ctx context.Context, key string) (*APIKeyInfo, error)
+
+func (fn KeyValidatorFunc) ValidateKey(ctx context.Context, key string) (*APIKeyInfo, error) {
+	return fn(ctx, key)
+}
+
+// StaticKeys is an in-memory KeyValidator mapping keys to metadata.
+type StaticKeys map[string]APIKeyInfo
+
+// ValidateKey compares in constant time so lookups don't leak key prefixes.
+func (s StaticKeys) ValidateKey(_ context.Context, key string) (*APIKeyInfo, error) {
+	var found *APIKeyInfo
+	for k, info := range s {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			info := info
+			found = &info
+		}
+	}
+	return found, nil
+}
+
+// APIKeyConfig configures the APIKey Step.
+type APIKeyConfig struct {
+	// Header to read the key from (defaults to "X-API-Key").
+	Header string
+	// Query parameter checked when the header is absent (disabled if empty).
+	Query     string
+	Validator KeyValidator
+}
+
+// APIKey returns a Step that authenticates requests with an API key and
+// stores its APIKeyInfo in the context. Missing or unknown keys get a 401.
+func APIKey(cfg APIKeyConfig) Step {
+	if cfg.Header == "" {
+		cfg.Header = "X-API-Key"
+	}
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		key := ctx.Request.Header.Get(cfg.Header)
+		if key == "" && cfg.Query != "" {
+			key = ctx.Request.URL.Query().Get(cfg.Query)
+		}
+		if key == "" {
+			ctx.fail(http.StatusUnauthorized, "missing api key")
+			return
+		}
+		info, err := cfg.Validator.ValidateKey(ctx.Request.Context(), key)
+		if err != nil || info == nil {
+			ctx.fail(http.StatusUnauthorized, "invalid api key")
+			return
+		}
+		ctx.Set(APIKeyInfoKey, info)
+		next(ctx)
+	})
+}
+
+// APIKey returns the metadata stored by the APIKey Step (nil if none).
+func (f *FlowContext) APIKey() *APIKeyInfo {
+	info, _ := f.Get(APIKeyInfoKey).(*APIKeyInfo)
+	return info
+}
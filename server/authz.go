@@ -0,0 +1,93 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Scopes returns the scopes granted to the authenticated caller, collected
+// from JWT claims ("scope", "scp", "scopes") and APIKeyInfo.Scopes.
+func (f *FlowContext) Scopes() []string {
+	var scopes []string
+	if c := f.Claims(); c != nil {
+		for _, name := range []string{"scope", "scp", "scopes"} {
+			scopes = append(scopes, claimList(c[name])...)
+		}
+	}
+	if info := f.APIKey(); info != nil {
+		scopes = append(scopes, info.Scopes...)
+	}
+	return scopes
+}
+
+// Roles returns the roles granted by the "roles" JWT claim.
+func (f *FlowContext) Roles() []string {
+	if c := f.Claims(); c != nil {
+		return claimList(c["roles"])
+	}
+	return nil
+}
+
+// RequireScopes returns a Step that only lets callers holding all the given
+// scopes through. Unauthenticated callers get a 401, others a 403.
+func RequireScopes(scopes ...string) Step {
+	return Authorize(func(ctx *FlowContext) bool {
+		return containsAll(ctx.Scopes(), scopes)
+	})
+}
+
+// RequireRoles returns a Step that lets callers holding any of the given roles through.
+func RequireRoles(roles ...string) Step {
+	return Authorize(func(ctx *FlowContext) bool {
+		have := ctx.Roles()
+		for _, r := range roles {
+			if slices.Contains(have, r) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Authorize returns a Step that runs a custom policy against the context.
+// Place it after the authentication Step (JWT, APIKey) on the route.
+func Authorize(policy func(ctx *FlowContext) bool) Step {
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		if ctx.Claims() == nil && ctx.APIKey() == nil {
+			ctx.fail(http.StatusUnauthorized, "unauthenticated")
+			return
+		}
+		if !policy(ctx) {
+			ctx.fail(http.StatusForbidden, "forbidden")
+			return
+		}
+		next(ctx)
+	})
+}
+
+// claimList normalizes a space-separated string or JSON array claim.
+func claimList(v any) []string {
+	switch t := v.(type) {
+	case string:
+		return strings.Fields(t)
+	case []any:
+		out := make([]string, 0, len(t))
+		for _, s := range t {
+			if str, ok := s.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func containsAll(have, want []string) bool {
+	for _, w := range want {
+		if !slices.Contains(have, w) {
+			return false
+		}
+	}
+	return true
+}
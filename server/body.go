@@ -0,0 +1,167 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// rawBodyLimit caps bodies read by ctx.RawBody.
+const rawBodyLimit = 10 << 20
+
+// RawBody reads the whole request body once and caches it, so signature
+// checks, logging and binders all see the same bytes. ctx.Request.Body is
+// reset to a fresh reader over them on every call. Bodies over the body
+// limit (see Route.MaxBody), or 10 MiB without one, fail with a 413
+// *HTTPError.
+func (f *FlowContext) RawBody() ([]byte, error) {
+	if !f.bodyRead {
+		limit := f.bodyLimit()
+		if limit <= 0 {
+			limit = rawBodyLimit
+		}
+		f.bodyRead = true
+		f.rawBody, f.bodyErr = readBody(f.Request.Body, limit)
+	}
+	if f.bodyErr != nil {
+		return nil, f.bodyErr
+	}
+	f.Request.Body = io.NopCloser(bytes.NewReader(f.rawBody))
+	return f.rawBody, nil
+}
+
+func readBody(body io.ReadCloser, limit int64) ([]byte, error) {
+	if body == nil || body == http.NoBody {
+		return nil, nil
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return nil, NewHTTPError(http.StatusRequestEntityTooLarge, "request body too large")
+	}
+	if err != nil {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Message: "failed to read request body", Err: err}
+	}
+	if int64(len(data)) > limit {
+		return nil, NewHTTPError(http.StatusRequestEntityTooLarge, "request body too large")
+	}
+	return data, nil
+}
+
+// MaxBody limits the route's request bodies to n bytes, overriding
+// WithMaxBodySize in either direction (e.g. 10 MiB for uploads, 64 KiB for
+// a JSON API). Requests declaring a larger Content-Length get a 413 before
+// any Step runs; longer bodies fail with a 413 when read.
+func (r *Route) MaxBody(n int64) *Route {
+	r.maxBody = n
+	return r
+}
+
+// bodyLimit returns the route's body limit, else the Flow's, else 0.
+func (f *FlowContext) bodyLimit() int64 {
+	if f.route != nil && f.route.maxBody > 0 {
+		return f.route.maxBody
+	}
+	if f.flow != nil {
+		return f.flow.maxBody
+	}
+	return 0
+}
+
+// limitBody enforces the body limit on the request, rejecting it if its
+// Content-Length is already too large.
+func (f *FlowContext) limitBody() error {
+	limit := f.bodyLimit()
+	if limit <= 0 || f.Request.Body == nil || f.Request.Body == http.NoBody {
+		return nil
+	}
+	if f.Request.ContentLength > limit {
+		return NewHTTPError(http.StatusRequestEntityTooLarge, "request body too large")
+	}
+	f.Request.Body = http.MaxBytesReader(f.Response, f.Request.Body, limit)
+	return nil
+}
+
+// BufferBody returns a Step that reads the request body up to limit bytes
+// (413 beyond it) so ctx.Request.Body can be re-read by later Steps and the
+// Sink: closing the body rewinds it for the next reader, and ctx.RawBody
+// serves the same bytes.
+func BufferBody(limit int64) Step {
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		if err := ctx.bufferBody(limit); err != nil {
+			ctx.Error(err)
+			return
+		}
+		next(ctx)
+	})
+}
+
+func (f *FlowContext) bufferBody(limit int64) error {
+	if !f.bodyRead {
+		f.bodyRead = true
+		f.rawBody, f.bodyErr = readBody(f.Request.Body, limit)
+	}
+	if f.bodyErr != nil {
+		return f.bodyErr
+	}
+	f.Request.Body = &replayBody{Reader: bytes.NewReader(f.rawBody)}
+	return nil
+}
+
+// replayBody is a buffered request body that rewinds when closed.
+type replayBody struct {
+	*bytes.Reader
+}
+
+func (b *replayBody) Close() error {
+	_, err := b.Seek(0, io.SeekStart)
+	return err
+}
+
+// Consumes restricts the media types the route accepts in request bodies,
+// e.g. r.Consumes("application/json") or r.Consumes("image/*"). Requests
+// with a body of another Content-Type get a 415 before any Step runs.
+func (r *Route) Consumes(types ...string) *Route {
+	r.consumes = append(r.consumes, types...)
+	return r
+}
+
+// checkContentType enforces the route's Consumes list.
+func (f *FlowContext) checkContentType() error {
+	if f.route == nil || len(f.route.consumes) == 0 {
+		return nil
+	}
+	if f.Request.Body == nil || f.Request.Body == http.NoBody || f.Request.ContentLength == 0 {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(f.Request.Header.Get("Content-Type"))
+	for _, t := range f.route.consumes {
+		if mediaTypeMatches(t, mediaType) {
+			return nil
+		}
+	}
+	if f.Request.Method == http.MethodPost {
+		f.Response.Header().Set("Accept-Post", strings.Join(f.route.consumes, ", "))
+	}
+	return NewHTTPError(http.StatusUnsupportedMediaType, "unsupported content type")
+}
+
+// mediaTypeMatches matches a media type against a pattern that may use
+// "*" for the subtype or the whole type.
+func mediaTypeMatches(pattern, mediaType string) bool {
+	if mediaType == "" {
+		return false
+	}
+	if pattern == "*/*" || strings.EqualFold(pattern, mediaType) {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		typ, _, _ := strings.Cut(mediaType, "/")
+		return strings.EqualFold(prefix, typ)
+	}
+	return false
+}
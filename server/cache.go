@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheMaxBody bounds how much of a response the Cache Step buffers;
+// larger (or flushed) responses are served but not cached.
+const cacheMaxBody = 4 << 20
+
+type cachedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// Cache returns a Step that stores successful GET responses (status, headers,
+// body) in store for ttl and replays them without running the handler.
+//
+// keyFunc defaults to the request URI; store defaults to a new MemoryStore.
+// Responses with a Vary header are cached per value of the varied request
+// headers. Responses carrying Set-Cookie, "Vary: *" or "Cache-Control:
+// no-store/private" are never cached. Requests with Authorization or Cookie
+// headers are never answered from the cache, and their responses are only
+// stored when marked "Cache-Control: public". Use InvalidateCache to drop an
+// entry early.
+func Cache(ttl time.Duration, keyFunc func(*FlowContext) string, store Store) Step {
+	if keyFunc == nil {
+		keyFunc = func(ctx *FlowContext) string { return ctx.Request.URL.RequestURI() }
+	}
+	if store == nil {
+		store = NewMemoryStore()
+	}
+
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		if ctx.Request.Method != http.MethodGet {
+			next(ctx)
+			return
+		}
+		rctx := ctx.Request.Context()
+		base := keyFunc(ctx)
+		credentialed := ctx.Request.Header.Get("Authorization") != "" || ctx.Request.Header.Get("Cookie") != ""
+
+		if !credentialed {
+			if vary, err := store.Get(rctx, cacheVaryKey(base)); err == nil && vary != nil {
+				data, err := store.Get(rctx, cacheEntryKey(base, string(vary), ctx.Request))
+				var cached cachedResponse
+				if err == nil && data != nil && json.Unmarshal(data, &cached) == nil {
+					h := ctx.Response.Header()
+					for k, v := range cached.Header {
+						h[k] = v
+					}
+					h.Set("X-Cache", "HIT")
+					ctx.Response.WriteHeader(cached.Status)
+					ctx.Response.Write(cached.Body)
+					return
+				}
+			}
+		}
+
+		orig := ctx.Response
+		bw := &bufferWriter{ResponseWriter: orig, limit: cacheMaxBody}
+		ctx.Response = bw
+		orig.Header().Set("X-Cache", "MISS")
+		next(ctx)
+		ctx.Response = orig
+
+		if !bw.passthrough && cacheable(bw.statusCode(), orig.Header()) &&
+			(!credentialed || headerHasToken(orig.Header(), "Cache-Control", "public")) {
+			header := orig.Header().Clone()
+			header.Del("X-Cache")
+			data, err := json.Marshal(cachedResponse{bw.statusCode(), header, bw.buf.Bytes()})
+			if err == nil {
+				vary := strings.Join(header.Values("Vary"), ",")
+				_ = store.Set(rctx, cacheVaryKey(base), []byte(vary), ttl)
+				_ = store.Set(rctx, cacheEntryKey(base, vary, ctx.Request), data, ttl)
+			}
+		}
+		bw.flush()
+	})
+}
+
+// InvalidateCache drops the cached responses (all Vary variants) for key,
+// where key is what the Cache Step's keyFunc returned.
+func InvalidateCache(ctx context.Context, store Store, key string) error {
+	return store.Delete(ctx, cacheVaryKey(key))
+}
+
+func cacheable(status int, h http.Header) bool {
+	if status != http.StatusOK || h.Get("Set-Cookie") != "" || headerHasToken(h, "Vary", "*") {
+		return false
+	}
+	cc := strings.ToLower(h.Get("Cache-Control"))
+	return !strings.Contains(cc, "no-store") && !strings.Contains(cc, "private")
+}
+
+func cacheVaryKey(base string) string { return "cache:vary:" + base }
+
+// cacheEntryKey appends the request's values for every varied header.
+func cacheEntryKey(base, vary string, r *http.Request) string {
+	var b strings.Builder
+	b.WriteString("cache:entry:")
+	b.WriteString(base)
+	for _, name := range strings.Split(vary, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			b.WriteString("|")
+			b.WriteString(strings.ToLower(name))
+			b.WriteString("=")
+			b.WriteString(r.Header.Get(name))
+		}
+	}
+	return b.String()
+}
+
+// CacheOptions describes a response's caching policy for ctx.CacheControl.
+type CacheOptions struct {
+	// MaxAge is how long any cache may reuse the response.
+	MaxAge time.Duration
+	// SMaxAge overrides MaxAge for shared caches (CDNs, proxies).
+	SMaxAge time.Duration
+	// Public allows shared caches to store responses to authenticated
+	// requests; Private restricts storage to the client.
+	Public  bool
+	Private bool
+	// NoCache requires revalidation before every reuse; NoStore forbids
+	// storing the response at all and overrides everything else.
+	NoCache bool
+	NoStore bool
+	// MustRevalidate forbids serving the response stale.
+	MustRevalidate bool
+	// Immutable promises the response never changes while fresh, e.g. for
+	// fingerprinted assets.
+	Immutable bool
+	// StaleWhileRevalidate lets caches serve a stale response while they
+	// refetch it in the background; StaleIfError while the origin fails.
+	StaleWhileRevalidate time.Duration
+	StaleIfError         time.Duration
+	// Vary lists request headers the response depends on.
+	Vary []string
+}
+
+// CacheControl sets Cache-Control, Expires and Vary from opts, e.g.
+//
+//	ctx.CacheControl(CacheOptions{Public: true, MaxAge: time.Hour, StaleWhileRevalidate: time.Minute})
+//
+// sends "Cache-Control: public, max-age=3600, stale-while-revalidate=60".
+func (f *FlowContext) CacheControl(opts CacheOptions) {
+	h := f.Response.Header()
+	for _, name := range opts.Vary {
+		if !headerHasToken(h, "Vary", name) {
+			h.Add("Vary", http.CanonicalHeaderKey(name))
+		}
+	}
+	if opts.NoStore {
+		h.Set("Cache-Control", "no-store")
+		h.Set("Expires", "0")
+		return
+	}
+
+	var d []string
+	switch {
+	case opts.Private:
+		d = append(d, "private")
+	case opts.Public:
+		d = append(d, "public")
+	}
+	if opts.NoCache {
+		d = append(d, "no-cache")
+	}
+	seconds := func(d time.Duration) string { return strconv.FormatInt(int64(d/time.Second), 10) }
+	if opts.MaxAge > 0 || !opts.NoCache {
+		d = append(d, "max-age="+seconds(opts.MaxAge))
+	}
+	if opts.SMaxAge > 0 {
+		d = append(d, "s-maxage="+seconds(opts.SMaxAge))
+	}
+	if opts.MustRevalidate {
+		d = append(d, "must-revalidate")
+	}
+	if opts.Immutable {
+		d = append(d, "immutable")
+	}
+	if opts.StaleWhileRevalidate > 0 {
+		d = append(d, "stale-while-revalidate="+seconds(opts.StaleWhileRevalidate))
+	}
+	if opts.StaleIfError > 0 {
+		d = append(d, "stale-if-error="+seconds(opts.StaleIfError))
+	}
+	h.Set("Cache-Control", strings.Join(d, ", "))
+	if opts.NoCache {
+		h.Set("Expires", "0")
+	} else {
+		h.Set("Expires", time.Now().Add(opts.MaxAge).UTC().Format(http.TimeFormat))
+	}
+}
+
+// headerHasToken reports whether a comma-separated header lists token.
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
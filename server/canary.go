@@ -0,0 +1,99 @@
+package server
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// CanaryKey is the ctx key set to true for requests the Canary Step sent
+// to the canary.
+const CanaryKey = "canary"
+
+// CanaryConfig configures the Canary Step. Set exactly one of Sink and
+// Upstream.
+type CanaryConfig struct {
+	// Percent of new clients assigned to the canary, 0-100.
+	Percent float64
+	// Sink handles canary requests in-process, e.g. a new handler version.
+	Sink Sink
+	// Upstream is a base URL canary requests are proxied to instead.
+	Upstream string
+	// Header, if set, lets a request pick its side regardless of the
+	// assignment: a true value (per strconv.ParseBool) forces the canary, a
+	// false one the stable path. Useful for testers and probes.
+	Header string
+	// Cookie keeps each client on its side (defaults to "flowhttp_canary").
+	Cookie string
+	// CookieTTL is how long an assignment sticks (defaults to 24h).
+	CookieTTL time.Duration
+}
+
+// Canary returns a Step that routes a percentage of clients to an
+// alternate Sink or upstream, for canary releases and A/B tests. Each
+// client's assignment is stored in a cookie, so it stays on one side; raise
+// Percent to move more new clients over.
+// It panics unless exactly one of Sink and Upstream is set.
+func Canary(cfg CanaryConfig) Step {
+	if (cfg.Sink == nil) == (cfg.Upstream == "") {
+		panic("canary: set exactly one of Sink and Upstream")
+	}
+	if cfg.Cookie == "" {
+		cfg.Cookie = "flowhttp_canary"
+	}
+	if cfg.CookieTTL <= 0 {
+		cfg.CookieTTL = 24 * time.Hour
+	}
+	canary := cfg.Sink
+	if cfg.Upstream != "" {
+		target, err := url.Parse(cfg.Upstream)
+		if err != nil {
+			panic("canary: invalid upstream: " + err.Error())
+		}
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		canary = func(ctx *FlowContext) { proxy.ServeHTTP(ctx.Response, ctx.Request) }
+	}
+
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		if useCanary(ctx, cfg) {
+			ctx.Set(CanaryKey, true)
+			canary(ctx)
+			return
+		}
+		next(ctx)
+	})
+}
+
+// useCanary decides the side of a request, assigning new clients.
+func useCanary(ctx *FlowContext, cfg CanaryConfig) bool {
+	if cfg.Header != "" {
+		if force, err := strconv.ParseBool(ctx.Request.Header.Get(cfg.Header)); err == nil {
+			return force
+		}
+	}
+	if c, err := ctx.Request.Cookie(cfg.Cookie); err == nil {
+		switch c.Value {
+		case "canary":
+			return true
+		case "stable":
+			return false
+		}
+	}
+	assigned := rand.Float64()*100 < cfg.Percent
+	value := "stable"
+	if assigned {
+		value = "canary"
+	}
+	http.SetCookie(ctx.Response, &http.Cookie{
+		Name:     cfg.Cookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(cfg.CookieTTL / time.Second),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return assigned
+}
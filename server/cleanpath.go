@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// WithCleanPaths normalizes request paths before routing: repeated slashes
+// are collapsed and "." and ".." segments resolved, so "/public/../admin"
+// can't reach a route by a path its Steps didn't expect. With redirect,
+// such requests are instead redirected to the clean path (301 for GET and
+// HEAD, 308 otherwise).
+func WithCleanPaths(redirect bool) Option {
+	return func(f *Flow) {
+		f.cleanPaths = true
+		f.cleanRedirect = redirect
+	}
+}
+
+// cleanPath is path.Clean that keeps a trailing slash.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	clean := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && clean != "/" {
+		clean += "/"
+	}
+	return clean
+}
+
+// cleanRequest returns req with a clean path, or false if it was answered
+// with a redirect.
+func (f *Flow) cleanRequest(w http.ResponseWriter, req *http.Request) (*http.Request, bool) {
+	clean := cleanPath(req.URL.Path)
+	if clean == req.URL.Path {
+		return req, true
+	}
+	u := *req.URL
+	u.Path, u.RawPath = clean, ""
+	if req.URL.RawPath != "" {
+		// keep escapes such as %2F when they survive cleaning intact
+		if raw := cleanPath(req.URL.RawPath); unescapesTo(raw, clean) {
+			u.RawPath = raw
+		}
+	}
+	if f.cleanRedirect {
+		code := http.StatusMovedPermanently
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			code = http.StatusPermanentRedirect
+		}
+		target := u.EscapedPath()
+		if u.RawQuery != "" {
+			target += "?" + u.RawQuery
+		}
+		http.Redirect(w, req, target, code)
+		return nil, false
+	}
+	r := new(http.Request)
+	*r = *req
+	r.URL = &u
+	return r, true
+}
+
+func unescapesTo(raw, p string) bool {
+	s, err := url.PathUnescape(raw)
+	return err == nil && s == p
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// coalescedCall is an in-flight leader request that waiters share.
+type coalescedCall struct {
+	done chan struct{}
+	resp *cachedResponse // nil if the response can't be shared
+}
+
+// Coalesce returns a Step that collapses concurrent identical GET requests:
+// the first runs the handler and every request with the same key that
+// arrives while it is running gets a copy of its response, marked
+// "X-Coalesced: true", instead of running the handler again.
+//
+// keyFunc defaults to the request URI, so include whatever makes responses
+// differ (user, tenant, varied headers) for personalised endpoints. Waiters
+// get only the headers the leader's handler set, not those earlier Steps
+// set on the leader's response. Responses larger than the Cache Step's
+// buffer, flushed ones, ones that set cookies and panics aren't shared;
+// waiters then run the handler themselves.
+func Coalesce(keyFunc func(*FlowContext) string) Step {
+	if keyFunc == nil {
+		keyFunc = func(ctx *FlowContext) string { return ctx.Request.URL.RequestURI() }
+	}
+	var (
+		mu    sync.Mutex
+		calls = make(map[string]*coalescedCall)
+	)
+
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		if ctx.Request.Method != http.MethodGet {
+			next(ctx)
+			return
+		}
+		key := keyFunc(ctx)
+
+		mu.Lock()
+		if c, ok := calls[key]; ok {
+			mu.Unlock()
+			select {
+			case <-c.done:
+			case <-ctx.Request.Context().Done():
+				return
+			}
+			if c.resp == nil {
+				next(ctx)
+				return
+			}
+			h := ctx.Response.Header()
+			for k, v := range c.resp.Header {
+				h[k] = v
+			}
+			h.Set("X-Coalesced", "true")
+			ctx.Response.WriteHeader(c.resp.Status)
+			ctx.Response.Write(c.resp.Body)
+			return
+		}
+		c := &coalescedCall{done: make(chan struct{})}
+		calls[key] = c
+		mu.Unlock()
+
+		orig := ctx.Response
+		before := orig.Header().Clone()
+		bw := &bufferWriter{ResponseWriter: orig, limit: cacheMaxBody}
+		ctx.Response = bw
+		defer func() {
+			ctx.Response = orig
+			p := recover()
+			if p == nil && !bw.passthrough && orig.Header().Get("Set-Cookie") == "" {
+				c.resp = &cachedResponse{bw.statusCode(), headerChanges(before, orig.Header()), bw.buf.Bytes()}
+			}
+			mu.Lock()
+			delete(calls, key)
+			mu.Unlock()
+			close(c.done)
+			if p != nil {
+				panic(p)
+			}
+			bw.flush()
+		}()
+		next(ctx)
+	})
+}
+
+// headerChanges returns the fields of after that were added or changed
+// since before, i.e. those set downstream of a Step. Headers set earlier in
+// the chain, such as a request ID, stay with their own request.
+func headerChanges(before, after http.Header) http.Header {
+	changed := make(http.Header)
+	for k, v := range after {
+		if !slices.Equal(before[k], v) {
+			changed[k] = slices.Clone(v)
+		}
+	}
+	return changed
+}
@@ -1,9 +1,14 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"strings"
+	"sync"
 )
 
 type ctxKey struct{}
@@ -17,16 +22,42 @@ type FlowContext struct {
 	Response http.ResponseWriter
 	local    map[string]any
 	Params   map[string]string
+	route    *Route
+	flow     *Flow
+	deferred []func()
+	rawBody  []byte
+	bodyRead bool
+	bodyErr  error
+	// jsonFailed is set once JSON fails to encode, so a failing error
+	// response can't recurse.
+	jsonFailed bool
 }
 
-// Set, Get, Delete are helpers to store small local values.
+// Set, Get, Delete are helpers to store small local values. Get falls back
+// to the route's Provider for key, if any, caching its result.
 func (f *FlowContext) Set(key string, value any) { f.local[key] = value }
-func (f *FlowContext) Get(key string) any        { return f.local[key] }
 func (f *FlowContext) Delete(key string)         { delete(f.local, key) }
 
-// Param returns a named path parameter (empty string if missing).
+func (f *FlowContext) Get(key string) any {
+	if v, ok := f.local[key]; ok {
+		return v
+	}
+	if f.route != nil {
+		if p := f.route.providers[key]; p != nil {
+			v := p(f)
+			f.local[key] = v
+			return v
+		}
+	}
+	return nil
+}
+
+// Param returns a named, percent-decoded path parameter (empty string if missing).
 func (f *FlowContext) Param(name string) string { return f.Params[name] }
 
+// Route returns the matched route (nil when the Sink runs outside a Flow).
+func (f *FlowContext) Route() *Route { return f.route }
+
 // Sink is the user handler type. ServeHTTP builds FlowContext from *http.Request.
 type Sink func(*FlowContext)
 
@@ -45,39 +76,132 @@ func (h Sink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			params = p
 		}
 	}
-	ctx := &FlowContext{
+	ctx := newFlowContext(w, r, params)
+	defer ctx.finish()
+	h(ctx)
+}
+
+// NewFlowContext builds a FlowContext outside a Flow, e.g. to unit-test a
+// Sink or Step directly. Funcs registered with Defer are not run.
+func NewFlowContext(w http.ResponseWriter, r *http.Request, params map[string]string) *FlowContext {
+	return newFlowContext(w, r, params)
+}
+
+func newFlowContext(w http.ResponseWriter, r *http.Request, params map[string]string) *FlowContext {
+	return &FlowContext{
 		Response: w,
 		Request:  r,
 		local:    make(map[string]any),
 		Params:   params,
 	}
-	h(ctx)
 }
 
+// jsonBuffers pools the buffers JSON encodes into.
+var jsonBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
 // JSON serializes the given data to JSON and writes it to the response.
-// It automatically sets the correct Content-Type header and handles encoding errors.
+// It automatically sets the correct Content-Type header. data is encoded
+// before anything is written, so an encoding failure is passed to ctx.Error
+// (as a 500) instead of corrupting a response already under way.
+// The Flow's WithJSONEncoder encoder is used when set.
 func (f *FlowContext) JSON(status int, data any) {
-	f.Response.Header().Set("Content-Type", "application/json")
-	f.Response.WriteHeader(status)
+	encode := func(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) }
+	if f.flow != nil && f.flow.jsonEncoder != nil {
+		encode = f.flow.jsonEncoder
+	}
+	buf := jsonBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= 64<<10 { // don't pin large buffers
+			jsonBuffers.Put(buf)
+		}
+	}()
 
-	if err := json.NewEncoder(f.Response).Encode(data); err != nil {
-		http.Error(f.Response, `{"error": "failed to encode JSON"}`, http.StatusInternalServerError)
+	if err := encode(buf, data); err != nil {
+		if f.jsonFailed {
+			// the error response itself failed to encode
+			http.Error(f.Response, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		f.jsonFailed = true
+		f.Error(fmt.Errorf("encode JSON response: %w", err))
+		return
 	}
+	f.Response.Header().Set("Content-Type", "application/json")
+	f.Response.WriteHeader(status)
+	f.Response.Write(buf.Bytes())
 }
 
 // BindJSON reads and parses JSON from the request body into the given struct/map.
+// The body stays available through ctx.RawBody for later readers. On failure
+// the error has already been answered via ctx.Error.
 func (f *FlowContext) BindJSON(v any) error {
-	body, err := io.ReadAll(f.Request.Body)
+	body, err := f.RawBody()
 	if err != nil {
-		http.Error(f.Response, "failed to read request body", http.StatusBadRequest)
+		f.Error(err)
 		return err
 	}
-	defer f.Request.Body.Close()
 
 	if err := json.Unmarshal(body, v); err != nil {
-		http.Error(f.Response, "invalid JSON", http.StatusBadRequest)
+		f.fail(http.StatusBadRequest, "invalid JSON")
 		return err
 	}
 
 	return nil
 }
+
+// DeclareTrailer announces trailer names in the Trailer header. Call it
+// before writing the body; clients and proxies then know to expect them.
+func (f *FlowContext) DeclareTrailer(keys ...string) {
+	for _, k := range keys {
+		f.Response.Header().Add("Trailer", http.CanonicalHeaderKey(k))
+	}
+}
+
+// SetTrailer sets a trailer value sent after the body, e.g. a checksum or
+// record count computed while streaming. Declared trailers are set
+// directly; undeclared ones use http.TrailerPrefix so they still go out
+// after the header has been written.
+func (f *FlowContext) SetTrailer(key, value string) {
+	key = http.CanonicalHeaderKey(key)
+	h := f.Response.Header()
+	for _, declared := range h.Values("Trailer") {
+		for _, name := range strings.Split(declared, ",") {
+			if http.CanonicalHeaderKey(strings.TrimSpace(name)) == key {
+				h.Set(key, value)
+				return
+			}
+		}
+	}
+	h.Set(http.TrailerPrefix+key, value)
+}
+
+// Defer registers fn to run after the response has been written, even if
+// the handler panics. Deferred funcs run in LIFO order on a separate
+// goroutine so they never delay the client; the request context is
+// canceled by then, so use context.WithoutCancel if fn needs its values.
+func (f *FlowContext) Defer(fn func()) {
+	f.deferred = append(f.deferred, fn)
+}
+
+// finish hands deferred funcs off once the handler chain has returned.
+func (f *FlowContext) finish() {
+	if len(f.deferred) == 0 {
+		return
+	}
+	fns, log := f.deferred, f.flow.logger()
+	go func() {
+		for i := len(fns) - 1; i >= 0; i-- {
+			runDeferred(log, fns[i])
+		}
+	}()
+}
+
+func runDeferred(log *slog.Logger, fn func()) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error("flowhttp: deferred func panicked", "panic", p)
+		}
+	}()
+	fn()
+}
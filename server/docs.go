@@ -0,0 +1,105 @@
+package server
+
+import (
+	"html/template"
+	"strings"
+)
+
+// Route metadata shared with the openapi package: SpecMetaKey marks the
+// route serving the document (set by openapi.Mount) and DocsMetaKey the
+// docs page, which the generator leaves out.
+const (
+	SpecMetaKey = "openapi.spec"
+	DocsMetaKey = "openapi.docs"
+)
+
+// swaggerUIBase is the pinned Swagger UI release ServeDocs loads by default.
+const swaggerUIBase = "https://unpkg.com/swagger-ui-dist@5.17.14"
+
+// DocsAssets locates the browser assets of ServeDocs and the GraphQL
+// playground, e.g. a self-hosted copy served with Static for networks
+// without CDN access or with a strict Content-Security-Policy.
+type DocsAssets struct {
+	// Base is the URL prefix holding the files, "/assets/swagger-ui" or
+	// "https://cdn.example.com/swagger-ui-dist@5.17.14" (defaults to the
+	// pinned release on unpkg). ServeDocs needs swagger-ui.css and
+	// swagger-ui-bundle.js; the playground needs graphiql.min.css,
+	// graphiql.min.js, react.production.min.js and
+	// react-dom.production.min.js.
+	Base string
+	// Integrity maps file names to Subresource Integrity hashes
+	// ("sha384-..."), which browsers check before using the file.
+	Integrity map[string]string
+}
+
+// asset is a file of a DocsAssets for a page template.
+type asset struct {
+	URL       string
+	Integrity string
+}
+
+// file returns the asset named name, under base when a.Base is empty.
+func (a DocsAssets) file(base, name string) asset {
+	if a.Base != "" {
+		base = strings.TrimSuffix(a.Base, "/")
+	}
+	return asset{URL: base + "/" + name, Integrity: a.Integrity[name]}
+}
+
+// WithDocsAssets makes ServeDocs load Swagger UI from a instead of unpkg;
+// the GraphQL playground takes its DocsAssets in GraphQLConfig.Assets.
+func WithDocsAssets(a DocsAssets) Option {
+	return func(f *Flow) { f.docsAssets = a }
+}
+
+var docsPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>API documentation</title>
+<link rel="stylesheet" href="{{.CSS.URL}}"{{with .CSS.Integrity}} integrity="{{.}}" crossorigin="anonymous"{{end}}>
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.JS.URL}}"{{with .JS.Integrity}} integrity="{{.}}"{{end}} crossorigin="anonymous"></script>
+<script>
+window.onload = () => {
+  window.ui = SwaggerUIBundle({ url: {{.Spec}}, dom_id: "#swagger-ui", deepLinking: true });
+};
+</script>
+</body>
+</html>
+`))
+
+// ServeDocs serves a Swagger UI page at path for the spec mounted with
+// openapi.Mount (or /openapi.json when none is mounted). Pass steps, such
+// as JWT or RequireRoles, to protect the docs. Swagger UI itself is loaded
+// from unpkg unless WithDocsAssets points elsewhere.
+func (b *Branch) ServeDocs(path string, steps ...Step) *Route {
+	r := b.Stream("GET", path, steps, func(ctx *FlowContext) {
+		ctx.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+		page := struct {
+			CSS, JS asset
+			Spec    string
+		}{
+			CSS:  b.flow.docsAssets.file(swaggerUIBase, "swagger-ui.css"),
+			JS:   b.flow.docsAssets.file(swaggerUIBase, "swagger-ui-bundle.js"),
+			Spec: b.flow.specURL(),
+		}
+		if err := docsPage.Execute(ctx.Response, page); err != nil {
+			ctx.flow.logger().Error("flowhttp: render docs", "error", err)
+		}
+	})
+	return r.SetMeta(DocsMetaKey, true)
+}
+
+// specURL returns the pattern of the route serving the OpenAPI document.
+func (f *Flow) specURL() string {
+	for _, r := range f.routes {
+		if r.Meta(SpecMetaKey) == true {
+			return r.Pattern
+		}
+	}
+	return "/openapi.json"
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"runtime/debug"
+)
+
+// HTTPError is an error carrying the status code to respond with.
+type HTTPError struct {
+	Code    int
+	Message string
+	// Details, if set, is sent alongside the message, e.g. a list of
+	// validation failures.
+	Details any
+	Err     error
+}
+
+// NewHTTPError creates an HTTPError; msg defaults to the status text.
+func NewHTTPError(code int, msg string) *HTTPError {
+	if msg == "" {
+		msg = http.StatusText(code)
+	}
+	return &HTTPError{Code: code, Message: msg}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *HTTPError) Unwrap() error { return e.Err }
+
+// ErrorHandler renders an error response.
+type ErrorHandler func(ctx *FlowContext, err error)
+
+// DefaultErrorHandler responds with {"error": message}. *HTTPError values
+// use their code and message, plus "details" when set; any other error
+// becomes a 500 with a generic message, and is logged rather than leaked
+// to the client.
+func DefaultErrorHandler(ctx *FlowContext, err error) {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		if he.Details != nil {
+			ctx.JSON(he.Code, map[string]any{"error": he.Message, "details": he.Details})
+			return
+		}
+		ctx.JSON(he.Code, map[string]string{"error": he.Message})
+		return
+	}
+	ctx.flow.logger().Error("flowhttp: handler error", "path", ctx.Request.URL.Path, "error", err)
+	ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+}
+
+// Error sends err through the Flow's error handler (see WithErrorHandler).
+// Errors that end in a 5xx are also reported to any ErrorNotifier.
+func (f *FlowContext) Error(err error) {
+	if isServerError(err) {
+		f.notify(err, debug.Stack())
+	}
+	f.handleError(err)
+}
+
+func (f *FlowContext) handleError(err error) {
+	if f.flow != nil && f.flow.errorHandler != nil {
+		f.flow.errorHandler(f, err)
+		return
+	}
+	DefaultErrorHandler(f, err)
+}
+
+// fail is shorthand for f.Error(NewHTTPError(code, msg)).
+func (f *FlowContext) fail(code int, msg string) {
+	f.Error(NewHTTPError(code, msg))
+}
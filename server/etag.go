@@ -0,0 +1,97 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETagConfig configures the ETag Step.
+type ETagConfig struct {
+	// Weak emits W/"..." validators (for bodies that are semantically, not
+	// byte-for-byte, equivalent).
+	Weak bool
+	// MaxBody caps how much of a response is buffered for hashing (defaults
+	// to 1 MiB). Larger or flushed responses stream through without an ETag.
+	MaxBody int
+}
+
+// ETag returns a Step that buffers successful GET/HEAD responses, sets an
+// ETag from the body hash (unless the handler set one), and answers
+// If-None-Match / If-Modified-Since with 304 Not Modified.
+func ETag(cfg ETagConfig) Step {
+	if cfg.MaxBody <= 0 {
+		cfg.MaxBody = 1 << 20
+	}
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		method := ctx.Request.Method
+		if method != http.MethodGet && method != http.MethodHead {
+			next(ctx)
+			return
+		}
+
+		orig := ctx.Response
+		bw := &bufferWriter{ResponseWriter: orig, limit: cfg.MaxBody}
+		ctx.Response = bw
+		next(ctx)
+		ctx.Response = orig
+
+		if bw.passthrough || bw.statusCode() != http.StatusOK {
+			bw.flush()
+			return
+		}
+
+		h := orig.Header()
+		etag := h.Get("ETag")
+		if etag == "" {
+			sum := sha256.Sum256(bw.buf.Bytes())
+			etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+			if cfg.Weak {
+				etag = "W/" + etag
+			}
+			h.Set("ETag", etag)
+		}
+
+		if notModified(ctx.Request, etag, h.Get("Last-Modified")) {
+			h.Del("Content-Type")
+			h.Del("Content-Length")
+			orig.WriteHeader(http.StatusNotModified)
+			return
+		}
+		bw.flush()
+	})
+}
+
+// notModified evaluates the conditional headers per RFC 9110 §13.2.2:
+// If-None-Match wins over If-Modified-Since when both are present.
+func notModified(r *http.Request, etag, lastModified string) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || weakMatch(candidate, etag) {
+				return true
+			}
+		}
+		return false
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// weakMatch compares entity tags ignoring the weak prefix.
+func weakMatch(a, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
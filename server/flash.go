@@ -0,0 +1,76 @@
+package server
+
+import "slices"
+
+// flashKey is the session value holding pending flash messages.
+const flashKey = "_flash"
+
+// FlashMessage is a one-shot message shown on the next page view.
+type FlashMessage struct {
+	Category string `json:"category"`
+	Message  string `json:"message"`
+}
+
+// Flash queues a message that survives until the next ctx.Flashes call,
+// typically across a post-redirect-get. It requires the Sessions Step.
+func (f *FlowContext) Flash(category, msg string) {
+	s := f.mustSession("Flash")
+	s.Set(flashKey, append(sessionFlashes(s), FlashMessage{category, msg}))
+}
+
+// Flashes returns and consumes pending flash messages, optionally limited to
+// the given categories (messages of other categories stay queued).
+func (f *FlowContext) Flashes(categories ...string) []FlashMessage {
+	s := f.mustSession("Flashes")
+	pending := sessionFlashes(s)
+	if len(pending) == 0 {
+		return nil
+	}
+	if len(categories) == 0 {
+		s.Delete(flashKey)
+		return pending
+	}
+
+	var out, keep []FlashMessage
+	for _, m := range pending {
+		if slices.Contains(categories, m.Category) {
+			out = append(out, m)
+		} else {
+			keep = append(keep, m)
+		}
+	}
+	if len(keep) == 0 {
+		s.Delete(flashKey)
+	} else {
+		s.Set(flashKey, keep)
+	}
+	return out
+}
+
+func (f *FlowContext) mustSession(caller string) *Session {
+	s := f.Session()
+	if s == nil {
+		panic("flowhttp: " + caller + " requires the Sessions step")
+	}
+	return s
+}
+
+// sessionFlashes reads queued messages both before (typed) and after
+// (decoded from JSON) a session round-trip.
+func sessionFlashes(s *Session) []FlashMessage {
+	switch v := s.Get(flashKey).(type) {
+	case []FlashMessage:
+		return v
+	case []any:
+		out := make([]FlashMessage, 0, len(v))
+		for _, item := range v {
+			if m, ok := item.(map[string]any); ok {
+				category, _ := m["category"].(string)
+				msg, _ := m["message"].(string)
+				out = append(out, FlashMessage{category, msg})
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -1,27 +1,91 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 type Branch struct {
-	path  string
-	steps []Step
-	flow  *Flow
+	path      string
+	steps     []Step
+	skip      []string
+	providers map[string]Provider
+	flow      *Flow
+	// versionSteps counts the leading steps that belong to the enclosing
+	// APIVersion, which APIVersion.Alias swaps for its own.
+	versionSteps int
 }
 
 // Flow is the top-level router object.
 type Flow struct {
 	streams        map[string]*streamMethods
 	dynamicStreams []dynamicStream
+	routes         []*Route
+	registry       map[string]Step
+	preRouting     []Step
+	modules        []Module
+	naming         *[]string // set while routeStepNames builds a chain
+	tlsConfig      *tls.Config
+	serverConfig   ServerConfig
+	notFound       Sink
+	errorHandler   ErrorHandler
+	jsonEncoder    JSONEncoder
+	trustedProxies []netip.Prefix
+	log            *slog.Logger
+	signals        []os.Signal
+	restart        bool
+	bodyBuffer     int64
+	maxBody        int64
+	cleanPaths     bool
+	cleanRedirect  bool
+	notifiers      []ErrorNotifier
+	bindings       map[reflect.Type]*binding
+	versions       map[string]*APIVersion
+	docsAssets     DocsAssets
+
+	shutdownTimeout time.Duration
+	drainTimeout    time.Duration
+	onShutdown      []func(context.Context)
+	onStart         []func(addr string)
+	onStop          []func()
+	longLived       sync.WaitGroup
+	jobs            jobQueue
+	scheduled       []*scheduledJob
+	scheduler       scheduler
+
+	mu       sync.Mutex
+	srv      *http.Server
+	addr     net.Addr
+	draining chan struct{}
+	stopped  chan struct{}
+	// shutdownErr is the first Shutdown's result, set before stopped closes
+	shutdownErr error
 	Branch
 }
 
-// NewFlow creates a root Flow.
-func NewFlow() *Flow {
-	f := &Flow{streams: make(map[string]*streamMethods)}
+// NewFlow creates a root Flow configured by opts.
+func NewFlow(opts ...Option) *Flow {
+	f := &Flow{
+		streams: make(map[string]*streamMethods),
+		signals: []os.Signal{os.Interrupt, syscall.SIGTERM},
+	}
 	f.flow = f
+	for _, opt := range opts {
+		opt(f)
+	}
 	return f
 }
 
@@ -31,20 +95,32 @@ func (b *Branch) Fork(path string, steps []Step) *Branch {
 		path = ""
 	}
 	return &Branch{
-		path:  b.path + path,
-		steps: append(b.steps, steps...),
-		flow:  b.flow,
+		path:         b.path + path,
+		steps:        append(b.steps, steps...),
+		skip:         slices.Clone(b.skip),
+		providers:    maps.Clone(b.providers),
+		flow:         b.flow,
+		versionSteps: b.versionSteps,
 	}
 }
 
 // ClearSteps clears inherited steps for this branch.
 func (b *Branch) ClearSteps() *Branch {
 	b.steps = nil
+	b.versionSteps = 0
+	return b
+}
+
+// Skip disables the named (registered) steps for streams declared on this
+// branch and its forks, even if inherited from a parent.
+func (b *Branch) Skip(names ...string) *Branch {
+	b.skip = append(b.skip, names...)
 	return b
 }
 
 // Stream registers a route handler for method+path under this branch.
-func (b *Branch) Stream(method string, path string, steps []Step, sink Sink) {
+// The returned Route describes the registration.
+func (b *Branch) Stream(method string, path string, steps []Step, sink Sink) *Route {
 	finalPath := b.path + path
 	finalSteps := append(b.steps, steps...)
 
@@ -52,11 +128,16 @@ func (b *Branch) Stream(method string, path string, steps []Step, sink Sink) {
 	if f.streams == nil {
 		f.streams = make(map[string]*streamMethods)
 	}
+	dynamic := strings.Contains(path, ":") || strings.Contains(path, "*")
 	m := f.streams[finalPath]
+	if dynamic {
+		m = f.dynamicMethods(finalPath)
+	}
 	if m == nil {
 		m = &streamMethods{}
 	}
-	h := &stream{steps: finalSteps, sink: sink}
+	route := &Route{Method: method, Pattern: finalPath, skip: slices.Clone(b.skip), providers: b.providers, steps: f.routeStepNames(finalSteps)}
+	h := &stream{steps: finalSteps, local: finalSteps[b.versionSteps:], sink: sink, route: route}
 
 	switch method {
 	case "GET":
@@ -66,13 +147,45 @@ func (b *Branch) Stream(method string, path string, steps []Step, sink Sink) {
 	default:
 		panic(fmt.Errorf("unsupported http method %s", method))
 	}
+	f.routes = append(f.routes, route)
 
 	// dynamic route detection uses original path fragment (not prefixed finalPath),
 	// so we check 'path' for params/wildcards to keep intent clear.
-	if strings.Contains(path, ":") || strings.Contains(path, "*") {
-		pattern, hasParams := convertPathToRegex(finalPath) // store compiled regex using finalPath
-		f.dynamicStreams = append(f.dynamicStreams, dynamicStream{pattern, m, hasParams})
+	if dynamic {
+		if f.dynamicMethods(finalPath) == nil {
+			pattern, hasParams := convertPathToRegex(finalPath) // store compiled regex using finalPath
+			f.dynamicStreams = append(f.dynamicStreams, dynamicStream{finalPath, pattern, m, hasParams})
+		}
 	} else {
 		f.streams[finalPath] = m
 	}
+	return route
+}
+
+// dynamicMethods returns the methods already registered for a dynamic
+// path, so GET and POST on the same pattern share one entry.
+func (f *Flow) dynamicMethods(path string) *streamMethods {
+	for _, d := range f.dynamicStreams {
+		if d.path == path {
+			return d.methods
+		}
+	}
+	return nil
+}
+
+// Provider lazily computes a per-request value; see Branch.Provide.
+type Provider func(ctx *FlowContext) any
+
+// Provide registers factory for key on streams declared afterwards on this
+// branch and its forks: the first ctx.Get(key) in a request calls it and
+// caches the result for the rest of the request, so values like a
+// tenant-scoped DB handle or the current user are only built when a
+// handler needs them. Values Set under key take precedence.
+func (b *Branch) Provide(key string, factory Provider) *Branch {
+	b.providers = maps.Clone(b.providers)
+	if b.providers == nil {
+		b.providers = make(map[string]Provider)
+	}
+	b.providers[key] = factory
+	return b
 }
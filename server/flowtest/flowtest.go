@@ -0,0 +1,113 @@
+// Package flowtest helps test Flows, Sinks and Steps without a listener.
+//
+//	resp := flowtest.Request(f, "POST", "/api/user", map[string]string{"name": "a"}, nil)
+//	if resp.Status != 201 { ... }
+//	var user User
+//	resp.JSON(&user)
+package flowtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/datanadhi/flowhttp/server"
+)
+
+// Response is a recorded response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// String returns the body as a string.
+func (r *Response) String() string { return string(r.Body) }
+
+// JSON decodes the body into v.
+func (r *Response) JSON(v any) error {
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		return fmt.Errorf("flowtest: decode %d response %q: %v", r.Status, r.Body, err)
+	}
+	return nil
+}
+
+// Map decodes a JSON object body, returning nil if it isn't one.
+func (r *Response) Map() map[string]any {
+	var m map[string]any
+	if json.Unmarshal(r.Body, &m) != nil {
+		return nil
+	}
+	return m
+}
+
+// Request sends a request through f and records the response. body may be
+// nil, a string, []byte or io.Reader (sent as is), or any other value, which
+// is sent as JSON with a matching Content-Type.
+func Request(f *server.Flow, method, path string, body any, headers map[string]string) *Response {
+	return record(f.Test(NewRequest(method, path, body, headers)))
+}
+
+// NewRequest builds a request the way Request does, for use with
+// NewContext or a handler directly.
+func NewRequest(method, path string, body any, headers map[string]string) *http.Request {
+	var r io.Reader
+	isJSON := false
+	switch b := body.(type) {
+	case nil:
+	case string:
+		r = strings.NewReader(b)
+	case []byte:
+		r = bytes.NewReader(b)
+	case io.Reader:
+		r = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			panic(fmt.Errorf("flowtest: encode body: %v", err))
+		}
+		r, isJSON = bytes.NewReader(data), true
+	}
+	req := httptest.NewRequest(method, path, r)
+	if isJSON {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req
+}
+
+// Recorder records what a Sink or Step built with NewContext writes.
+type Recorder struct {
+	*httptest.ResponseRecorder
+}
+
+// Result returns what has been recorded so far.
+func (r *Recorder) Result() *Response { return record(r.ResponseRecorder) }
+
+// NewContext builds a FlowContext for req with the given path params, for
+// calling a Sink or Step without routing:
+//
+//	ctx, rec := flowtest.NewContext(flowtest.NewRequest("GET", "/user/1", nil, nil), map[string]string{"id": "1"})
+//	getUser(ctx)
+//	resp := rec.Result()
+func NewContext(req *http.Request, params map[string]string) (*server.FlowContext, *Recorder) {
+	rec := httptest.NewRecorder()
+	return server.NewFlowContext(rec, req, params), &Recorder{rec}
+}
+
+// RunStep runs step on ctx with a no-op next Sink and reports whether the
+// step passed the request on. Check the Recorder for what it wrote.
+func RunStep(step server.Step, ctx *server.FlowContext) (calledNext bool) {
+	step(func(*server.FlowContext) { calledNext = true })(ctx)
+	return calledNext
+}
+
+func record(rec *httptest.ResponseRecorder) *Response {
+	return &Response{Status: rec.Code, Header: rec.Header().Clone(), Body: bytes.Clone(rec.Body.Bytes())}
+}
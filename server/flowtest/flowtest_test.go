@@ -0,0 +1,147 @@
+package flowtest
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/datanadhi/flowhttp/server"
+)
+
+// echoFlow answers POST /echo with the request's Content-Type, X-Test
+// header and body, and GET /user/:id with a JSON object.
+func echoFlow() *server.Flow {
+	f := server.NewFlow()
+	f.Stream("POST", "/echo", nil, func(ctx *server.FlowContext) {
+		body, _ := io.ReadAll(ctx.Request.Body)
+		ctx.Response.Header().Set("X-Test", ctx.Request.Header.Get("X-Test"))
+		ctx.Response.Write([]byte(ctx.Request.Header.Get("Content-Type") + "|" + string(body)))
+	})
+	f.Stream("GET", "/user/:id", nil, func(ctx *server.FlowContext) {
+		ctx.Response.Header().Set("Content-Type", "application/json")
+		ctx.Response.Write([]byte(`{"id":"` + ctx.Param("id") + `"}`))
+	})
+	return f
+}
+
+func TestRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    any
+		headers map[string]string
+		want    string
+	}{
+		{name: "nil", want: "|"},
+		{name: "string", body: "raw", want: "|raw"},
+		{name: "bytes", body: []byte("raw"), want: "|raw"},
+		{name: "reader", body: strings.NewReader("raw"), want: "|raw"},
+		{name: "json", body: map[string]string{"name": "a"}, want: `application/json|{"name":"a"}`},
+		{name: "header overrides content type", body: map[string]string{"name": "a"},
+			headers: map[string]string{"Content-Type": "text/plain"}, want: `text/plain|{"name":"a"}`},
+		{name: "headers", body: "raw", headers: map[string]string{"X-Test": "yes"}, want: "|raw"},
+	}
+	f := echoFlow()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := Request(f, "POST", "/echo", tt.body, tt.headers)
+			if resp.Status != http.StatusOK {
+				t.Fatalf("status = %d, want %d", resp.Status, http.StatusOK)
+			}
+			if got := resp.String(); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+			if got, want := resp.Header.Get("X-Test"), tt.headers["X-Test"]; got != want {
+				t.Errorf("X-Test = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestRequestPanicsOnUnencodableBody(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewRequest did not panic")
+		}
+	}()
+	NewRequest("POST", "/echo", make(chan int), nil)
+}
+
+func TestResponseDecoding(t *testing.T) {
+	f := echoFlow()
+	resp := Request(f, "GET", "/user/7", nil, nil)
+	var user struct{ ID string }
+	if err := resp.JSON(&user); err != nil || user.ID != "7" {
+		t.Errorf("JSON = %+v, %v, want ID 7", user, err)
+	}
+	if m := resp.Map(); m["id"] != "7" {
+		t.Errorf("Map = %v, want id 7", m)
+	}
+
+	resp = Request(f, "POST", "/echo", "not json", nil)
+	if err := resp.JSON(&user); err == nil || !strings.Contains(err.Error(), "not json") {
+		t.Errorf("JSON error = %v, want one quoting the body", err)
+	}
+	if m := resp.Map(); m != nil {
+		t.Errorf("Map = %v, want nil", m)
+	}
+}
+
+func TestNewContextAndRunStep(t *testing.T) {
+	deny := server.CreateStep(func(next server.Sink, ctx *server.FlowContext) {
+		if ctx.Request.Header.Get("X-Token") != "ok" {
+			ctx.Response.WriteHeader(http.StatusForbidden)
+			return
+		}
+		next(ctx)
+	})
+	tests := []struct {
+		name   string
+		token  string
+		next   bool
+		status int
+	}{
+		{name: "passes", token: "ok", next: true, status: http.StatusOK},
+		{name: "stops", token: "no", next: false, status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, rec := NewContext(NewRequest("GET", "/user/1", nil, map[string]string{"X-Token": tt.token}), map[string]string{"id": "1"})
+			if got := ctx.Param("id"); got != "1" {
+				t.Errorf("Param(id) = %q, want 1", got)
+			}
+			if got := RunStep(deny, ctx); got != tt.next {
+				t.Errorf("RunStep = %v, want %v", got, tt.next)
+			}
+			if got := rec.Result().Status; got != tt.status {
+				t.Errorf("status = %d, want %d", got, tt.status)
+			}
+		})
+	}
+}
+
+func TestAssertRoutes(t *testing.T) {
+	f := echoFlow()
+	AssertRoutes(t, f, map[string]string{
+		"GET /user/1":  "/user/:id",
+		"POST /echo":   "/echo",
+		"GET /echo":    "",
+		"GET /missing": "",
+	})
+
+	// a wrong expectation must fail
+	rec := &recordingT{TB: t}
+	AssertRoute(rec, f, "GET", "/user/1", "/user/me")
+	if !rec.failed {
+		t.Error("AssertRoute passed on a wrong pattern")
+	}
+}
+
+// recordingT notes failures instead of failing the test.
+type recordingT struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recordingT) Helper()               {}
+func (r *recordingT) Errorf(string, ...any) { r.failed = true }
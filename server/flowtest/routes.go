@@ -0,0 +1,54 @@
+package flowtest
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/datanadhi/flowhttp/server"
+)
+
+// AssertRoute fails t unless method+path resolves to the route registered
+// with pattern. An empty pattern asserts that the request matches no route.
+func AssertRoute(t testing.TB, f *server.Flow, method, path, pattern string) {
+	t.Helper()
+	route, _ := f.Match(method, path)
+	got := ""
+	if route != nil {
+		got = route.Pattern
+	}
+	if got != pattern {
+		t.Errorf("%s %s: routed to %s, want %s", method, path, describe(got), describe(pattern))
+	}
+}
+
+// AssertRoutes checks many requests at once. Keys are "METHOD /path" and
+// values the expected route pattern ("" for no match):
+//
+//	flowtest.AssertRoutes(t, f, map[string]string{
+//		"GET /api/user/1":  "/api/user/:id",
+//		"GET /api/user/me": "/api/user/me",
+//		"POST /api/user/1": "",
+//	})
+func AssertRoutes(t testing.TB, f *server.Flow, expected map[string]string) {
+	t.Helper()
+	keys := make([]string, 0, len(expected))
+	for k := range expected {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		method, path, ok := strings.Cut(k, " ")
+		if !ok {
+			t.Fatalf("flowtest: route key %q must be \"METHOD /path\"", k)
+		}
+		AssertRoute(t, f, method, path, expected[k])
+	}
+}
+
+func describe(pattern string) string {
+	if pattern == "" {
+		return "no route"
+	}
+	return pattern
+}
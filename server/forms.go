@@ -0,0 +1,262 @@
+package server
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CSRFKey is the ctx key under which the CSRF Step stores the request's token.
+const CSRFKey = "csrf.token"
+
+const (
+	csrfSessionKey = "_csrf"
+	csrfFieldKey   = "csrf.field"
+	oldInputKey    = "_old_input"
+	formErrorsKey  = "_form_errors"
+	formDataKey    = "form.data"
+)
+
+// CSRFConfig configures the CSRF Step.
+type CSRFConfig struct {
+	// Field is the form field carrying the token (defaults to "_csrf").
+	Field string
+	// Header is checked first, for scripts (defaults to "X-CSRF-Token").
+	Header string
+}
+
+// CSRF returns a Step protecting forms against cross-site request forgery
+// with a per-session token: POST requests must echo it in the form field
+// or header, or get a 403. Render it with ctx.Form().CSRFField(). Form
+// bodies are read through ctx.RawBody, so handlers can still parse them. It
+// requires the Sessions Step.
+func CSRF(cfg CSRFConfig) Step {
+	if cfg.Field == "" {
+		cfg.Field = "_csrf"
+	}
+	if cfg.Header == "" {
+		cfg.Header = "X-CSRF-Token"
+	}
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		s := ctx.mustSession("CSRF")
+		token, _ := s.Get(csrfSessionKey).(string)
+		if token == "" {
+			token = newSessionID()
+			s.Set(csrfSessionKey, token)
+		}
+		ctx.Set(CSRFKey, token)
+		ctx.Set(csrfFieldKey, cfg.Field)
+
+		switch ctx.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			sent := ctx.Request.Header.Get(cfg.Header)
+			if sent == "" {
+				var err error
+				if sent, err = formField(ctx, cfg.Field); err != nil {
+					ctx.Error(err)
+					return
+				}
+			}
+			if subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+				ctx.fail(http.StatusForbidden, "invalid CSRF token")
+				return
+			}
+		}
+		next(ctx)
+	})
+}
+
+// formField reads name from a urlencoded or multipart body via
+// ctx.RawBody, so the body stays readable for the handler. Other bodies
+// have no form fields.
+func formField(ctx *FlowContext, name string) (string, error) {
+	mediaType, params, _ := mime.ParseMediaType(ctx.Request.Header.Get("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" && mediaType != "multipart/form-data" {
+		return "", nil
+	}
+	body, err := ctx.RawBody()
+	if err != nil {
+		return "", err
+	}
+	if mediaType == "application/x-www-form-urlencoded" {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "", nil
+		}
+		return values.Get(name), nil
+	}
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return "", nil
+		}
+		if part.FormName() == name && part.FileName() == "" {
+			value, _ := io.ReadAll(io.LimitReader(part, 1<<10))
+			return string(value), nil
+		}
+	}
+}
+
+// CSRFToken returns the token set by the CSRF Step, or "".
+func (f *FlowContext) CSRFToken() string {
+	t, _ := f.Get(CSRFKey).(string)
+	return t
+}
+
+// FlashForm keeps a rejected form's input and per-field errors for the
+// next request, typically the page redirected back to, where ctx.Form()
+// returns them. Password fields and the CSRF token are not kept. It
+// requires the Sessions Step.
+func (f *FlowContext) FlashForm(input url.Values, errs map[string]string) {
+	s := f.mustSession("FlashForm")
+	field, _ := f.Get(csrfFieldKey).(string)
+	old := make(url.Values, len(input))
+	for k, v := range input {
+		if k != field && !strings.Contains(strings.ToLower(k), "password") {
+			old[k] = v
+		}
+	}
+	s.Set(oldInputKey, old)
+	s.Set(formErrorsKey, errs)
+}
+
+// Form returns the form state for rendering: the CSRF token and any input
+// and errors kept by FlashForm on the previous request (consumed here).
+func (f *FlowContext) Form() *FormData {
+	if fd, ok := f.Get(formDataKey).(*FormData); ok {
+		return fd
+	}
+	fd := &FormData{Token: f.CSRFToken(), Old: url.Values{}, Errors: map[string]string{}}
+	fd.Field, _ = f.Get(csrfFieldKey).(string)
+	if s := f.Session(); s != nil {
+		fd.Old = sessionValues(s.Get(oldInputKey))
+		errs := sessionValues(s.Get(formErrorsKey))
+		for k := range errs {
+			fd.Errors[k] = errs.Get(k)
+		}
+		if s.Get(oldInputKey) != nil || s.Get(formErrorsKey) != nil {
+			s.Delete(oldInputKey)
+			s.Delete(formErrorsKey)
+		}
+	}
+	f.Set(formDataKey, fd)
+	return fd
+}
+
+// sessionValues reads url.Values or map[string]string stored in a session,
+// before or after its JSON round-trip.
+func sessionValues(v any) url.Values {
+	out := url.Values{}
+	switch v := v.(type) {
+	case url.Values:
+		return v
+	case map[string]string:
+		for k, s := range v {
+			out.Set(k, s)
+		}
+	case map[string]any:
+		for k, item := range v {
+			switch item := item.(type) {
+			case string:
+				out.Set(k, item)
+			case []any:
+				for _, s := range item {
+					out.Add(k, fmt.Sprint(s))
+				}
+			}
+		}
+	}
+	return out
+}
+
+// FormData is the state a server-rendered form needs.
+type FormData struct {
+	Token  string
+	Field  string
+	Old    url.Values
+	Errors map[string]string
+}
+
+// Value returns the previously submitted value of name, or "".
+func (d *FormData) Value(name string) string { return d.Old.Get(name) }
+
+// Error returns the validation error for name, or "".
+func (d *FormData) Error(name string) string { return d.Errors[name] }
+
+// HasErrors reports whether the previous submission was rejected.
+func (d *FormData) HasErrors() bool { return len(d.Errors) > 0 }
+
+// CSRFField renders the hidden CSRF input.
+func (d *FormData) CSRFField() template.HTML {
+	field := d.Field
+	if field == "" {
+		field = "_csrf"
+	}
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(field) +
+		`" value="` + template.HTMLEscapeString(d.Token) + `">`)
+}
+
+// Input renders a labelled input of type typ, refilled with the previous
+// value (except passwords) and followed by its error, if any:
+//
+//	<label for="email">Email</label>
+//	<input type="email" id="email" name="email" value="a@b" aria-invalid="true">
+//	<p class="field-error">is invalid</p>
+func (d *FormData) Input(typ, name, label string) template.HTML {
+	var b bytes.Buffer
+	esc := template.HTMLEscapeString
+	fmt.Fprintf(&b, `<label for="%s">%s</label>`, esc(name), esc(label))
+	fmt.Fprintf(&b, `<input type="%s" id="%s" name="%s"`, esc(typ), esc(name), esc(name))
+	if typ != "password" {
+		if v := d.Value(name); v != "" {
+			fmt.Fprintf(&b, ` value="%s"`, esc(v))
+		}
+	}
+	if msg := d.Error(name); msg != "" {
+		fmt.Fprintf(&b, ` aria-invalid="true"><p class="field-error">%s</p>`, esc(msg))
+	} else {
+		b.WriteString(">")
+	}
+	return template.HTML(b.String())
+}
+
+// FormFuncs returns template funcs taking a *FormData (from ctx.Form()):
+// {{csrfField .Form}}, {{old .Form "email"}}, {{fieldError .Form "email"}}
+// and {{input .Form "email" "email" "Email"}}.
+func FormFuncs() template.FuncMap {
+	return template.FuncMap{
+		"csrfField":  (*FormData).CSRFField,
+		"old":        (*FormData).Value,
+		"fieldError": (*FormData).Error,
+		"input":      (*FormData).Input,
+	}
+}
+
+// HTML executes the template name of t (t itself when name is "") with
+// data and sends the result. Rendering happens before anything is
+// written, so template errors go to ctx.Error.
+func (f *FlowContext) HTML(status int, t *template.Template, name string, data any) {
+	var buf bytes.Buffer
+	var err error
+	if name == "" {
+		err = t.Execute(&buf, data)
+	} else {
+		err = t.ExecuteTemplate(&buf, name, data)
+	}
+	if err != nil {
+		f.Error(fmt.Errorf("render template: %w", err))
+		return
+	}
+	f.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	f.Response.WriteHeader(status)
+	f.Response.Write(buf.Bytes())
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// csrfFlow serves the CSRF token at GET /form and echoes the posted body
+// at POST /form, both behind Sessions and CSRF.
+func csrfFlow() *Flow {
+	f := NewFlow()
+	steps := []Step{Sessions(SessionConfig{}), CSRF(CSRFConfig{})}
+	f.Stream("GET", "/form", steps, func(ctx *FlowContext) {
+		ctx.Response.Write([]byte(ctx.CSRFToken()))
+	})
+	f.Stream("POST", "/form", steps, func(ctx *FlowContext) {
+		body, _ := io.ReadAll(ctx.Request.Body)
+		ctx.Response.Write(body)
+	})
+	return f
+}
+
+func TestCSRF(t *testing.T) {
+	f := csrfFlow()
+	rec := f.Test(httptest.NewRequest("GET", "/form", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	token := rec.Body.String()
+	if token == "" {
+		t.Fatal("GET returned no token")
+	}
+	cookies := rec.Result().Cookies()
+
+	multipartBody := func(field, value string) (string, string) {
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		w.WriteField("name", "ada")
+		w.WriteField(field, value)
+		w.Close()
+		return buf.String(), w.FormDataContentType()
+	}
+	validMultipart, multipartType := multipartBody("_csrf", token)
+	wrongMultipart, _ := multipartBody("_csrf", "wrong")
+
+	form := func(token string) string { return url.Values{"name": {"ada"}, "_csrf": {token}}.Encode() }
+	tests := []struct {
+		name        string
+		session     bool
+		header      string
+		contentType string
+		body        string
+		status      int
+	}{
+		{name: "header", session: true, header: token, status: http.StatusOK},
+		{name: "form field", session: true, contentType: "application/x-www-form-urlencoded", body: form(token), status: http.StatusOK},
+		{name: "multipart field", session: true, contentType: multipartType, body: validMultipart, status: http.StatusOK},
+		{name: "header wins over field", session: true, header: token,
+			contentType: "application/x-www-form-urlencoded", body: form("wrong"), status: http.StatusOK},
+		{name: "missing", session: true, status: http.StatusForbidden},
+		{name: "wrong header", session: true, header: "wrong", status: http.StatusForbidden},
+		{name: "wrong form field", session: true, contentType: "application/x-www-form-urlencoded", body: form("wrong"), status: http.StatusForbidden},
+		{name: "wrong multipart field", session: true, contentType: multipartType, body: wrongMultipart, status: http.StatusForbidden},
+		{name: "field in json body", session: true, contentType: "application/json", body: `{"_csrf":"` + token + `"}`, status: http.StatusForbidden},
+		{name: "other session", header: token, status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/form", strings.NewReader(tt.body))
+			if tt.session {
+				for _, c := range cookies {
+					req.AddCookie(c)
+				}
+			}
+			if tt.header != "" {
+				req.Header.Set("X-CSRF-Token", tt.header)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := f.Test(req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			// the handler still sees the body the Step read
+			if got := rec.Body.String(); tt.status == http.StatusOK && got != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
+
+func TestCSRFSafeMethods(t *testing.T) {
+	f := csrfFlow()
+	for _, method := range []string{"GET", "HEAD"} {
+		if rec := f.Test(httptest.NewRequest(method, "/form", nil)); rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want %d", method, rec.Code, http.StatusOK)
+		}
+	}
+}
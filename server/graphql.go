@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+type flowContextKey struct{}
+
+// FlowContextFrom returns the FlowContext attached to a request context by
+// GraphQL, so resolvers can reach claims, sessions and locals. It returns
+// nil if none is attached.
+func FlowContextFrom(ctx context.Context) *FlowContext {
+	fc, _ := ctx.Value(flowContextKey{}).(*FlowContext)
+	return fc
+}
+
+// GraphQLConfig configures GraphQL.
+type GraphQLConfig struct {
+	// Playground serves a GraphiQL page to browsers on GET.
+	Playground bool
+	// Context optionally derives the context resolvers see, e.g. to copy
+	// claims or a request ID under the keys a schema already uses. The
+	// FlowContext itself is always available via FlowContextFrom.
+	Context func(ctx *FlowContext, parent context.Context) context.Context
+	// Assets locates the playground's GraphiQL and React files (defaults to
+	// pinned releases on unpkg).
+	Assets DocsAssets
+}
+
+// Pinned releases the GraphiQL playground loads by default.
+const (
+	graphiqlBase = "https://unpkg.com/graphiql@3.7.1"
+	reactBase    = "https://unpkg.com/react@18.3.1/umd"
+	reactDOMBase = "https://unpkg.com/react-dom@18.3.1/umd"
+)
+
+var graphiqlPage = template.Must(template.New("graphiql").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>GraphiQL</title>
+<style>body { margin: 0; height: 100vh; } #graphiql { height: 100vh; }</style>
+<link rel="stylesheet" href="{{.CSS.URL}}"{{with .CSS.Integrity}} integrity="{{.}}" crossorigin="anonymous"{{end}}>
+</head>
+<body>
+<div id="graphiql"></div>
+{{range .Scripts}}<script src="{{.URL}}"{{with .Integrity}} integrity="{{.}}"{{end}} crossorigin="anonymous"></script>
+{{end}}<script>
+const fetcher = GraphiQL.createFetcher({ url: {{.Endpoint}} });
+ReactDOM.createRoot(document.getElementById("graphiql")).render(React.createElement(GraphiQL, { fetcher }));
+</script>
+</body>
+</html>
+`))
+
+// GraphQL mounts a GraphQL handler (gqlgen, graphql-go, ...) at path for
+// both GET and POST, with steps such as JWT running first. The handler sees
+// a request whose context carries the FlowContext.
+func (b *Branch) GraphQL(path string, h http.Handler, cfg GraphQLConfig, steps ...Step) {
+	endpoint := b.path + path
+	sink := func(ctx *FlowContext) {
+		r := ctx.Request
+		if cfg.Playground && r.Method == http.MethodGet && r.URL.RawQuery == "" &&
+			strings.Contains(r.Header.Get("Accept"), "text/html") {
+			ctx.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+			page := struct {
+				CSS      asset
+				Scripts  []asset
+				Endpoint string
+			}{
+				CSS: cfg.Assets.file(graphiqlBase, "graphiql.min.css"),
+				Scripts: []asset{
+					cfg.Assets.file(reactBase, "react.production.min.js"),
+					cfg.Assets.file(reactDOMBase, "react-dom.production.min.js"),
+					cfg.Assets.file(graphiqlBase, "graphiql.min.js"),
+				},
+				Endpoint: endpoint,
+			}
+			if err := graphiqlPage.Execute(ctx.Response, page); err != nil {
+				ctx.flow.logger().Error("flowhttp: render graphiql", "error", err)
+			}
+			return
+		}
+		c := context.WithValue(r.Context(), flowContextKey{}, ctx)
+		if cfg.Context != nil {
+			c = cfg.Context(ctx, c)
+		}
+		h.ServeHTTP(ctx.Response, r.WithContext(c))
+	}
+	b.Stream("GET", path, steps, sink)
+	b.Stream("POST", path, steps, sink)
+}
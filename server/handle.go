@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// RequestTypeMetaKey and ResponseTypeMetaKey are the route metadata keys
+// holding the reflect.Type of a Handle route's Req and Resp, which the
+// openapi package documents.
+const (
+	RequestTypeMetaKey  = "openapi.request"
+	ResponseTypeMetaKey = "openapi.response"
+)
+
+// Validator is implemented by request types that check themselves after
+// binding. Errors become 422 responses unless they are *HTTPError.
+type Validator interface {
+	Validate() error
+}
+
+// Handle registers a typed handler. The request is bound into a Req: the
+// JSON body (when present), then fields tagged `path:"name"` and
+// `query:"name"`; if Req implements Validator it is validated. fn's result
+// is rendered as JSON with status 200, and its error via ctx.Error. Req and
+// Resp are recorded on the route for openapi.
+//
+//	type GetUser struct {
+//		ID int `path:"id"`
+//	}
+//	flowhttp.Handle(api, "GET", "/user/:id", func(ctx *flowhttp.FlowContext, req GetUser) (User, error) {
+//		return users.Find(req.ID)
+//	})
+func Handle[Req, Resp any](b *Branch, method, path string, fn func(ctx *FlowContext, req Req) (Resp, error), steps ...Step) *Route {
+	r := b.Stream(method, path, steps, func(ctx *FlowContext) {
+		var req Req
+		if err := bindRequest(ctx, &req); err != nil {
+			ctx.Error(err)
+			return
+		}
+		if v, ok := any(&req).(Validator); ok {
+			if err := v.Validate(); err != nil {
+				ctx.Error(validationError(err))
+				return
+			}
+		}
+		resp, err := fn(ctx, req)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.JSON(http.StatusOK, resp)
+	})
+	r.SetMeta(RequestTypeMetaKey, reflect.TypeFor[Req]())
+	r.SetMeta(ResponseTypeMetaKey, reflect.TypeFor[Resp]())
+	return r
+}
+
+func validationError(err error) error {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return err
+	}
+	return &HTTPError{Code: http.StatusUnprocessableEntity, Message: err.Error(), Err: err}
+}
+
+// bindRequest decodes the JSON body, if any, into v and then applies path
+// and query tags.
+func bindRequest(ctx *FlowContext, v any) error {
+	body, err := ctx.RawBody()
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, v); err != nil {
+			return &HTTPError{Code: http.StatusBadRequest, Message: "invalid JSON", Err: err}
+		}
+	}
+	rv := reflect.ValueOf(v).Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	query := ctx.Request.URL.Query()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		var values []string
+		name := field.Tag.Get("path")
+		if name != "" {
+			if p, ok := ctx.Params[name]; ok {
+				values = []string{p}
+			}
+		} else if name = field.Tag.Get("query"); name != "" {
+			values = query[name]
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if err := setField(rv.Field(i), values); err != nil {
+			return &HTTPError{Code: http.StatusBadRequest, Message: fmt.Sprintf("invalid %s", name), Err: err}
+		}
+	}
+	return nil
+}
+
+// setField parses values into a string, bool, numeric or slice field.
+func setField(f reflect.Value, values []string) error {
+	if f.Kind() == reflect.Slice {
+		s := reflect.MakeSlice(f.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := setField(s.Index(i), []string{v}); err != nil {
+				return err
+			}
+		}
+		f.Set(s)
+		return nil
+	}
+	v := values[0]
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(v)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(v, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(v, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(v, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+// Package health serves Kubernetes-style liveness (/healthz) and readiness
+// (/readyz) probes backed by named checks.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/datanadhi/flowhttp/server"
+)
+
+// Check is a named probe.
+type Check struct {
+	Name string
+	Func func(ctx context.Context) error
+	// Threshold is how many consecutive failures it takes before the check
+	// is reported down (defaults to 1), to ride out transient blips.
+	Threshold int
+	// Timeout bounds a single run (defaults to Health.Timeout).
+	Timeout time.Duration
+}
+
+// probe tracks consecutive failures of a registered Check.
+type probe struct {
+	Check
+	mu       sync.Mutex
+	failures int
+}
+
+// Result is the per-check entry of a probe response.
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+	Failures  int     `json:"consecutive_failures,omitempty"`
+}
+
+// Report is the probe response body.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Router is satisfied by *server.Flow and *server.Branch.
+type Router interface {
+	Stream(method string, path string, steps []server.Step, sink server.Sink) *server.Route
+}
+
+// Health holds registered checks.
+type Health struct {
+	// Timeout is the default per-check timeout (defaults to 2s).
+	Timeout time.Duration
+
+	mu    sync.RWMutex
+	live  []*probe
+	ready []*probe
+}
+
+// New creates an empty Health.
+func New() *Health {
+	return &Health{Timeout: 2 * time.Second}
+}
+
+// Liveness registers a check served by /healthz. Liveness checks also
+// count toward readiness.
+func (h *Health) Liveness(c Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.live = append(h.live, &probe{Check: c})
+}
+
+// Readiness registers a check served by /readyz only.
+func (h *Health) Readiness(c Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ready = append(h.ready, &probe{Check: c})
+}
+
+// Mount registers GET /healthz and GET /readyz on r.
+func (h *Health) Mount(r Router, steps ...server.Step) {
+	r.Stream(http.MethodGet, "/healthz", steps, h.LiveSink())
+	r.Stream(http.MethodGet, "/readyz", steps, h.ReadySink())
+}
+
+// LiveSink serves the liveness report.
+func (h *Health) LiveSink() server.Sink {
+	return func(ctx *server.FlowContext) {
+		h.mu.RLock()
+		checks := append([]*probe(nil), h.live...)
+		h.mu.RUnlock()
+		h.respond(ctx, checks)
+	}
+}
+
+// ReadySink serves the readiness report.
+func (h *Health) ReadySink() server.Sink {
+	return func(ctx *server.FlowContext) {
+		h.mu.RLock()
+		checks := append(append([]*probe(nil), h.live...), h.ready...)
+		h.mu.RUnlock()
+		h.respond(ctx, checks)
+	}
+}
+
+func (h *Health) respond(ctx *server.FlowContext, checks []*probe) {
+	report := h.run(ctx.Request.Context(), checks)
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.Response.Header().Set("Cache-Control", "no-store")
+	ctx.JSON(status, report)
+}
+
+// run executes checks concurrently and aggregates the results.
+func (h *Health) run(ctx context.Context, checks []*probe) Report {
+	report := Report{Status: "ok", Checks: make(map[string]Result, len(checks))}
+	results := make([]Result, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.runOne(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		report.Checks[c.Name] = results[i]
+		if results[i].Status != "ok" {
+			report.Status = "fail"
+		}
+	}
+	return report
+}
+
+func (h *Health) runOne(ctx context.Context, c *probe) Result {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = h.Timeout
+	}
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	cctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := call(cctx, c.Func)
+	res := Result{Status: "ok", LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+
+	threshold := c.Threshold
+	if threshold <= 0 {
+		threshold = 1
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		c.failures = 0
+		return res
+	}
+	c.failures++
+	res.Error = err.Error()
+	res.Failures = c.failures
+	if c.failures >= threshold {
+		res.Status = "fail"
+	}
+	return res
+}
+
+// call runs check in its own goroutine so that one ignoring its context
+// still fails at the timeout, and turns a panic into an error instead of
+// crashing the process.
+func call(ctx context.Context, check func(context.Context) error) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("check did not finish: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,299 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	localeKey = "i18n.locale"
+	i18nKey   = "i18n.bundle"
+)
+
+// I18n holds message bundles per language tag.
+type I18n struct {
+	// Default is the fallback language (e.g. "en").
+	Default string
+	// QueryParam and Cookie name the request overrides checked before
+	// Accept-Language (default "lang" for both; "-" disables).
+	QueryParam string
+	Cookie     string
+
+	bundles map[string]map[string]string
+}
+
+// NewI18n creates an empty I18n falling back to defaultLang.
+func NewI18n(defaultLang string) *I18n {
+	return &I18n{Default: defaultLang, bundles: make(map[string]map[string]string)}
+}
+
+// AddMessages merges messages into the bundle for lang.
+func (i *I18n) AddMessages(lang string, messages map[string]string) {
+	lang = strings.ToLower(lang)
+	b := i.bundles[lang]
+	if b == nil {
+		b = make(map[string]string)
+		i.bundles[lang] = b
+	}
+	for k, v := range messages {
+		b[k] = v
+	}
+}
+
+// LoadFS loads every <lang>.json and <lang>.toml file in dir of fsys.
+// Nested JSON objects and TOML tables become dotted keys ("errors.not_found").
+func (i *I18n) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		ext := path.Ext(e.Name())
+		if e.IsDir() || (ext != ".json" && ext != ".toml") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		messages := make(map[string]string)
+		if ext == ".json" {
+			var raw map[string]any
+			if err := json.Unmarshal(data, &raw); err != nil {
+				return fmt.Errorf("%s: %w", e.Name(), err)
+			}
+			flattenMessages("", raw, messages)
+		} else if err := parseTOMLMessages(string(data), messages); err != nil {
+			return fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		i.AddMessages(strings.TrimSuffix(e.Name(), ext), messages)
+	}
+	return nil
+}
+
+// Translate looks key up for lang, falling back to the base language
+// ("pt-BR" -> "pt"), then Default, then the key itself. With args, the
+// message is used as a fmt format string.
+func (i *I18n) Translate(lang, key string, args ...any) string {
+	msg, ok := i.lookup(lang, key)
+	if !ok {
+		msg, ok = i.lookup(i.Default, key)
+	}
+	if !ok {
+		msg = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+func (i *I18n) lookup(lang, key string) (string, bool) {
+	lang = strings.ToLower(lang)
+	if msg, ok := i.bundles[lang][key]; ok {
+		return msg, true
+	}
+	if base, _, found := strings.Cut(lang, "-"); found {
+		msg, ok := i.bundles[base][key]
+		return msg, ok
+	}
+	return "", false
+}
+
+// Match picks the best available language for the given preferences.
+func (i *I18n) Match(prefs []string) string {
+	for _, p := range prefs {
+		p = strings.ToLower(p)
+		if _, ok := i.bundles[p]; ok {
+			return p
+		}
+		if base, _, found := strings.Cut(p, "-"); found {
+			if _, ok := i.bundles[base]; ok {
+				return base
+			}
+		}
+	}
+	return i.Default
+}
+
+// Locale returns a Step that negotiates the request language (query param,
+// then cookie, then Accept-Language) and enables ctx.T and ctx.Locale.
+func (i *I18n) Locale() Step {
+	query, cookie := i.QueryParam, i.Cookie
+	if query == "" {
+		query = "lang"
+	}
+	if cookie == "" {
+		cookie = "lang"
+	}
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		var prefs []string
+		if query != "-" {
+			if v := ctx.Request.URL.Query().Get(query); v != "" {
+				prefs = append(prefs, v)
+			}
+		}
+		if cookie != "-" {
+			if c, err := ctx.Request.Cookie(cookie); err == nil && c.Value != "" {
+				prefs = append(prefs, c.Value)
+			}
+		}
+		prefs = append(prefs, parseAcceptLanguage(ctx.Request.Header.Get("Accept-Language"))...)
+
+		lang := i.Match(prefs)
+		ctx.Set(i18nKey, i)
+		ctx.Set(localeKey, lang)
+		ctx.Response.Header().Set("Content-Language", lang)
+		next(ctx)
+	})
+}
+
+// Locale returns the negotiated language (empty without the Locale Step).
+func (f *FlowContext) Locale() string {
+	l, _ := f.Get(localeKey).(string)
+	return l
+}
+
+// T translates key into the negotiated language. Pass it to templates as a
+// func to translate there too. Without the Locale Step it returns the key.
+func (f *FlowContext) T(key string, args ...any) string {
+	i, _ := f.Get(i18nKey).(*I18n)
+	if i == nil {
+		if len(args) > 0 {
+			return fmt.Sprintf(key, args...)
+		}
+		return key
+	}
+	return i.Translate(f.Locale(), key, args...)
+}
+
+// AcceptedLanguage is a language tag from Accept-Language with its q-value.
+type AcceptedLanguage struct {
+	Tag string
+	Q   float64
+}
+
+// AcceptedLanguages returns the request's Accept-Language tags, highest
+// q-value first (header order for ties), without "*" and q=0 entries. It
+// needs no Locale Step, for apps that only pick a locale themselves.
+func (f *FlowContext) AcceptedLanguages() []AcceptedLanguage {
+	return acceptedLanguages(f.Request.Header.Get("Accept-Language"))
+}
+
+func acceptedLanguages(header string) []AcceptedLanguage {
+	var tags []AcceptedLanguage
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.TrimSpace(name)
+		if name == "" || name == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			tags = append(tags, AcceptedLanguage{name, q})
+		}
+	}
+	sort.SliceStable(tags, func(a, b int) bool { return tags[a].Q > tags[b].Q })
+	return tags
+}
+
+// parseAcceptLanguage returns the tags of acceptedLanguages.
+func parseAcceptLanguage(header string) []string {
+	tags := acceptedLanguages(header)
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = t.Tag
+	}
+	return out
+}
+
+func flattenMessages(prefix string, src map[string]any, dst map[string]string) {
+	for k, v := range src {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch t := v.(type) {
+		case string:
+			dst[key] = t
+		case map[string]any:
+			flattenMessages(key, t, dst)
+		default:
+			dst[key] = fmt.Sprint(t)
+		}
+	}
+}
+
+// parseTOMLMessages reads the string-valued subset of TOML used by message
+// bundles: [table] headers, key = "basic" or 'literal' strings, # comments.
+func parseTOMLMessages(src string, dst map[string]string) error {
+	table := ""
+	for n, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return fmt.Errorf("line %d: malformed table header", n+1)
+			}
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("line %d: expected key = value", n+1)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		value = strings.TrimSpace(value)
+		var str string
+		switch {
+		case strings.HasPrefix(value, `"`):
+			end := closingQuote(value)
+			if end < 0 {
+				return fmt.Errorf("line %d: unterminated string", n+1)
+			}
+			s, err := strconv.Unquote(value[:end+1])
+			if err != nil {
+				return fmt.Errorf("line %d: %w", n+1, err)
+			}
+			str = s
+		case strings.HasPrefix(value, "'"):
+			end := strings.IndexByte(value[1:], '\'')
+			if end < 0 {
+				return fmt.Errorf("line %d: unterminated string", n+1)
+			}
+			str = value[1 : end+1]
+		default:
+			return fmt.Errorf("line %d: only string values are supported", n+1)
+		}
+		if table != "" {
+			key = table + "." + key
+		}
+		dst[key] = str
+	}
+	return nil
+}
+
+// closingQuote finds the index of the unescaped quote ending a basic string.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyConfig configures the Idempotency Step.
+type IdempotencyConfig struct {
+	// Store keeps recorded responses (defaults to a new MemoryStore).
+	Store Store
+	// TTL is how long a key can be replayed (defaults to 24h).
+	TTL time.Duration
+	// Header carrying the key (defaults to "Idempotency-Key").
+	Header string
+	// Scope optionally namespaces keys, e.g. by authenticated user.
+	Scope func(ctx *FlowContext) string
+	// LockTTL bounds how long an in-flight key blocks retries if the
+	// instance handling it dies (defaults to 1 minute).
+	LockTTL time.Duration
+}
+
+type idempotentRecord struct {
+	Pending     bool            `json:"pending,omitempty"`
+	Fingerprint string          `json:"fingerprint"`
+	Response    *cachedResponse `json:"response,omitempty"`
+}
+
+// Idempotency returns a Step that records responses keyed by the
+// Idempotency-Key header and replays them on retries within TTL, marking
+// them with "Idempotent-Replayed: true".
+//
+// Requests without the header pass through. Reusing a key with a different
+// body gets a 422; retrying while the first request is still running, on
+// this instance or another sharing the Store, gets a 409. If the Store
+// can't take the key's lock the request fails with a 500 instead of
+// running unguarded. 5xx responses are not recorded, so they can be
+// retried.
+func Idempotency(cfg IdempotencyConfig) Step {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.Header == "" {
+		cfg.Header = "Idempotency-Key"
+	}
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = time.Minute
+	}
+	var inflight sync.Map
+
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		idemKey := ctx.Request.Header.Get(cfg.Header)
+		if idemKey == "" {
+			next(ctx)
+			return
+		}
+		key := "idem:" + ctx.Request.Method + ":" + ctx.Request.URL.Path + ":" + idemKey
+		if cfg.Scope != nil {
+			key += ":" + cfg.Scope(ctx)
+		}
+		rctx := ctx.Request.Context()
+
+		body, err := ctx.RawBody()
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		sum := sha256.Sum256(body)
+		fingerprint := hex.EncodeToString(sum[:])
+
+		// in-process guard first; the store's SetNX covers other instances
+		if _, busy := inflight.LoadOrStore(key, struct{}{}); busy {
+			ctx.fail(http.StatusConflict, "request with this idempotency key is in progress")
+			return
+		}
+		defer inflight.Delete(key)
+
+		if data, err := cfg.Store.Get(rctx, key); err == nil && data != nil {
+			var rec idempotentRecord
+			if json.Unmarshal(data, &rec) == nil {
+				switch {
+				case rec.Fingerprint != fingerprint:
+					ctx.fail(http.StatusUnprocessableEntity, "idempotency key reused with a different request")
+					return
+				case rec.Pending:
+					ctx.fail(http.StatusConflict, "request with this idempotency key is in progress")
+					return
+				case rec.Response != nil:
+					h := ctx.Response.Header()
+					for k, v := range rec.Response.Header {
+						h[k] = v
+					}
+					h.Set("Idempotent-Replayed", "true")
+					ctx.Response.WriteHeader(rec.Response.Status)
+					ctx.Response.Write(rec.Response.Body)
+					return
+				}
+			}
+		}
+
+		pending, _ := json.Marshal(idempotentRecord{Pending: true, Fingerprint: fingerprint})
+		locked, err := cfg.Store.SetNX(rctx, key, pending, cfg.LockTTL)
+		if err != nil {
+			ctx.Error(fmt.Errorf("idempotency: lock %s: %w", idemKey, err))
+			return
+		}
+		if !locked {
+			ctx.fail(http.StatusConflict, "request with this idempotency key is in progress")
+			return
+		}
+		log := ctx.flow.logger()
+
+		orig := ctx.Response
+		bw := &bufferWriter{ResponseWriter: orig}
+		ctx.Response = bw
+		defer func() {
+			ctx.Response = orig
+			unlock := func() {
+				if err := cfg.Store.Delete(context.WithoutCancel(rctx), key); err != nil {
+					log.Error("flowhttp: release idempotency key", "key", idemKey, "error", err)
+				}
+			}
+			if p := recover(); p != nil {
+				unlock()
+				panic(p)
+			}
+			status := bw.statusCode()
+			if status >= 500 || bw.passthrough {
+				unlock()
+			} else {
+				resp := &cachedResponse{status, orig.Header().Clone(), bw.buf.Bytes()}
+				data, err := json.Marshal(idempotentRecord{Fingerprint: fingerprint, Response: resp})
+				if err == nil {
+					err = cfg.Store.Set(context.WithoutCancel(rctx), key, data, cfg.TTL)
+				}
+				if err != nil {
+					// the pending marker keeps blocking retries until LockTTL
+					log.Error("flowhttp: record idempotent response", "key", idemKey, "error", err)
+				}
+			}
+			bw.flush()
+		}()
+		next(ctx)
+	})
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+const injectScopeKey = "inject.scope"
+
+// binding is a registered dependency: an app-wide singleton built once, or
+// a request-scoped value built once per request.
+type binding struct {
+	once   sync.Once
+	app    func() (any, error)
+	scoped func(*FlowContext) (any, error)
+	value  any
+	err    error
+}
+
+// scopedValue is a request-scoped dependency resolved for one request.
+type scopedValue struct {
+	value   any
+	err     error
+	pending bool
+}
+
+// Singleton registers factory as the app-wide provider of T (a DB pool, a
+// service). It runs once, on first resolution; its result or error is
+// shared by every request.
+func Singleton[T any](f *Flow, factory func() (T, error)) {
+	f.bind(reflect.TypeFor[T](), &binding{app: func() (any, error) { return factory() }})
+}
+
+// Scoped registers factory as the per-request provider of T (a transaction,
+// a tenant-bound repository). It runs at most once per request and may
+// Resolve other dependencies from ctx.
+func Scoped[T any](f *Flow, factory func(ctx *FlowContext) (T, error)) {
+	f.bind(reflect.TypeFor[T](), &binding{scoped: func(ctx *FlowContext) (any, error) { return factory(ctx) }})
+}
+
+func (f *Flow) bind(t reflect.Type, b *binding) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.bindings == nil {
+		f.bindings = make(map[reflect.Type]*binding)
+	}
+	f.bindings[t] = b
+}
+
+// Resolve returns the T registered with Singleton or Scoped.
+func Resolve[T any](ctx *FlowContext) (T, error) {
+	var zero T
+	v, err := ctx.resolve(reflect.TypeFor[T]())
+	if err != nil {
+		return zero, err
+	}
+	t, _ := v.(T)
+	return t, nil
+}
+
+func (f *FlowContext) resolve(t reflect.Type) (any, error) {
+	if f.flow == nil {
+		return nil, fmt.Errorf("no dependencies outside a Flow")
+	}
+	f.flow.mu.Lock()
+	b := f.flow.bindings[t]
+	f.flow.mu.Unlock()
+	if b == nil {
+		return nil, fmt.Errorf("no provider registered for %v", t)
+	}
+	if b.app != nil {
+		b.once.Do(func() { b.value, b.err = b.app() })
+		return b.value, b.err
+	}
+
+	scope, _ := f.local[injectScopeKey].(map[reflect.Type]*scopedValue)
+	if scope == nil {
+		scope = make(map[reflect.Type]*scopedValue)
+		f.local[injectScopeKey] = scope
+	}
+	if r, ok := scope[t]; ok {
+		if r.pending {
+			return nil, fmt.Errorf("dependency cycle resolving %v", t)
+		}
+		return r.value, r.err
+	}
+	r := &scopedValue{pending: true}
+	scope[t] = r
+	r.value, r.err = b.scoped(f)
+	r.pending = false
+	return r.value, r.err
+}
+
+// Inject adapts a handler that declares its dependencies as the exported
+// fields of the struct D; each field is resolved by type before fn runs,
+// and fields tagged `inject:"-"` are left alone:
+//
+//	type deps struct {
+//		DB    *sql.DB
+//		Users *UserService
+//	}
+//	f.Stream("GET", "/users", nil, server.Inject(func(ctx *server.FlowContext, d deps) { ... }))
+//
+// Resolution errors are passed to ctx.Error. It panics if D isn't a struct.
+func Inject[D any](fn func(ctx *FlowContext, deps D)) Sink {
+	t := reflect.TypeFor[D]()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Errorf("inject: %v is not a struct", t))
+	}
+	var fields []int
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.IsExported() && field.Tag.Get("inject") != "-" {
+			fields = append(fields, i)
+		}
+	}
+	return func(ctx *FlowContext) {
+		var deps D
+		v := reflect.ValueOf(&deps).Elem()
+		for _, i := range fields {
+			dep, err := ctx.resolve(t.Field(i).Type)
+			if err != nil {
+				ctx.Error(fmt.Errorf("inject %s: %w", t.Field(i).Name, err))
+				return
+			}
+			if dep != nil {
+				v.Field(i).Set(reflect.ValueOf(dep))
+			}
+		}
+		fn(ctx, deps)
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// IsolateConfig configures the Isolate Step.
+type IsolateConfig struct {
+	// Name identifies the subtree, e.g. a plugin's name; panics are
+	// reported as "<Name>: panic: ...".
+	Name string
+	// Notifier, if set, receives the subtree's panics instead of the
+	// Flow's and route's notifiers, e.g. to route a plugin's crashes to
+	// its vendor.
+	Notifier ErrorNotifier
+	// OnPanic renders the response instead of the Flow's error handler.
+	OnPanic ErrorHandler
+}
+
+// Isolate returns a Step that recovers panics in the Steps and Sink after
+// it, typically put on the Fork third-party handlers or plugins are
+// mounted on. The panic is reported and answered by cfg's notifier and
+// handler, so the subtree can be handled apart from the rest of the Flow
+// without touching WithErrorHandler or WithErrorNotifier, which sibling
+// routes keep using.
+func Isolate(cfg IsolateConfig) Step {
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			var err error = &PanicError{Value: p}
+			if cfg.Name != "" {
+				err = fmt.Errorf("%s: %w", cfg.Name, err)
+			}
+			if cfg.Notifier != nil {
+				cfg.Notifier.Notify(ctx, err, debug.Stack())
+			} else {
+				ctx.notify(err, debug.Stack())
+			}
+			if cfg.OnPanic != nil {
+				cfg.OnPanic(ctx, err)
+				return
+			}
+			ctx.handleError(err)
+		}()
+		next(ctx)
+	})
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// ErrQueueFull is returned by Enqueue when the job queue has no room.
+var ErrQueueFull = errors.New("job queue full")
+
+// Job is a unit of background work. Its context is canceled if the job is
+// still running when the shutdown drain times out.
+type Job func(ctx context.Context) error
+
+// jobQueue is the Flow's background worker pool. Workers start with the
+// first job and are drained by Shutdown.
+type jobQueue struct {
+	mu      sync.Mutex
+	workers int
+	size    int
+	queue   chan Job
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// WithWorkers sizes the background job pool behind Enqueue: n workers
+// (defaults to runtime.NumCPU()) and room for queue pending jobs (defaults
+// to 1024).
+func WithWorkers(n, queue int) Option {
+	return func(f *Flow) { f.jobs.workers, f.jobs.size = n, queue }
+}
+
+// Enqueue schedules job on the Flow's worker pool. Failed and panicking
+// jobs are logged. It returns ErrQueueFull instead of blocking when the
+// queue is full. Shutdown waits for queued jobs, up to the shutdown
+// timeout, before running OnShutdown hooks.
+func (f *Flow) Enqueue(job Job) error {
+	q := &f.jobs
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.queue == nil {
+		q.start(f)
+	}
+	select {
+	case q.queue <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Enqueue schedules job to run after the handler, e.g. sending a mail once
+// the response is out. The job's context keeps the request's values but
+// not its cancellation.
+func (f *FlowContext) Enqueue(job Job) error {
+	if f.flow == nil {
+		return errors.New("enqueue outside a Flow")
+	}
+	values := context.WithoutCancel(f.Request.Context())
+	return f.flow.Enqueue(func(ctx context.Context) error {
+		jctx, cancel := context.WithCancel(values)
+		defer cancel()
+		defer context.AfterFunc(ctx, cancel)()
+		return job(jctx)
+	})
+}
+
+func (q *jobQueue) start(f *Flow) {
+	workers, size := q.workers, q.size
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if size <= 0 {
+		size = 1024
+	}
+	q.queue = make(chan Job, size)
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	for range workers {
+		q.wg.Add(1)
+		go func(queue <-chan Job, ctx context.Context) {
+			defer q.wg.Done()
+			for job := range queue {
+				f.runJob(ctx, job)
+			}
+		}(q.queue, q.ctx)
+	}
+}
+
+func (f *Flow) runJob(ctx context.Context, job Job) {
+	defer func() {
+		if p := recover(); p != nil {
+			f.logger().Error("background job panicked", "panic", p)
+		}
+	}()
+	if err := job(ctx); err != nil {
+		f.logger().Error("background job failed", "error", err)
+	}
+}
+
+// drainJobs stops accepting jobs and waits for queued ones until ctx is
+// done, then cancels those still running. The next Enqueue starts a fresh
+// pool.
+func (f *Flow) drainJobs(ctx context.Context) {
+	q := &f.jobs
+	q.mu.Lock()
+	queue, cancel := q.queue, q.cancel
+	q.queue = nil
+	q.mu.Unlock()
+	if queue == nil {
+		return
+	}
+	close(queue)
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		f.logger().Warn("background jobs still running at shutdown")
+	}
+	cancel()
+}
@@ -0,0 +1,376 @@
+package server
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimeFormat selects how JSONConventions encodes time.Time.
+type TimeFormat int
+
+const (
+	TimeRFC3339   TimeFormat = iota // "2006-01-02T15:04:05.999999999Z07:00", as encoding/json
+	TimeUnix                        // seconds since the epoch
+	TimeUnixMilli                   // milliseconds since the epoch
+)
+
+// DurationFormat selects how JSONConventions encodes time.Duration.
+type DurationFormat int
+
+const (
+	DurationNanos   DurationFormat = iota // integer nanoseconds, as encoding/json
+	DurationString                        // "1h2m3.5s"
+	DurationSeconds                       // float seconds
+	DurationMillis                        // integer milliseconds
+)
+
+// JSONConventions are app-wide rules for encoding times and durations, so
+// every endpoint serializes them alike without per-type MarshalJSON.
+type JSONConventions struct {
+	Time     TimeFormat
+	Duration DurationFormat
+	// Location, if set, converts times before encoding, e.g. time.UTC.
+	Location *time.Location
+}
+
+// WithJSONConventions makes ctx.JSON and ctx.JSONStream apply c. It wraps
+// the encoder set by a preceding WithJSONEncoder.
+func WithJSONConventions(c JSONConventions) Option {
+	return func(f *Flow) { f.jsonEncoder = c.Encoder(f.jsonEncoder) }
+}
+
+// Encoder returns a JSONEncoder that rewrites times and durations in v,
+// honoring json struct tags, and hands the result to next (encoding/json
+// when nil). Values implementing json.Marshaler or encoding.TextMarshaler
+// encode themselves. Like encoding/json, it fails on cyclic values.
+func (c JSONConventions) Encoder(next JSONEncoder) JSONEncoder {
+	if next == nil {
+		next = func(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) }
+	}
+	return func(w io.Writer, v any) error {
+		conv := &converter{JSONConventions: c}
+		out, err := conv.convert(reflect.ValueOf(v))
+		if err != nil {
+			return err
+		}
+		return next(w, out)
+	}
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	durationType      = reflect.TypeFor[time.Duration]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// converter rewrites one value. seen holds the pointers, maps and slices
+// on the path being converted, so cycles are reported instead of
+// recursing forever.
+type converter struct {
+	JSONConventions
+	seen map[seenKey]bool
+}
+
+type seenKey struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+func (c *converter) convert(v reflect.Value) (any, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	switch v.Type() {
+	case timeType:
+		t := v.Interface().(time.Time)
+		if c.Location != nil {
+			t = t.In(c.Location)
+		}
+		switch c.Time {
+		case TimeUnix:
+			return t.Unix(), nil
+		case TimeUnixMilli:
+			return t.UnixMilli(), nil
+		}
+		return t, nil
+	case durationType:
+		d := time.Duration(v.Int())
+		switch c.Duration {
+		case DurationString:
+			return d.String(), nil
+		case DurationSeconds:
+			return d.Seconds(), nil
+		case DurationMillis:
+			return d.Milliseconds(), nil
+		}
+		return d, nil
+	}
+	if marshalsItself(v) {
+		return v.Interface(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		key := seenKey{v.Type(), v.Pointer(), 0}
+		if v.Kind() == reflect.Slice {
+			key.len = v.Len()
+		}
+		if c.seen[key] {
+			return nil, fmt.Errorf("json: unsupported value: encountered a cycle via %s", v.Type())
+		}
+		if c.seen == nil {
+			c.seen = make(map[seenKey]bool)
+		}
+		c.seen[key] = true
+		defer delete(c.seen, key)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return c.convert(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface(), nil
+		}
+		out := make([]any, v.Len())
+		for i := range out {
+			var err error
+			if out[i], err = c.convert(v.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case reflect.Map:
+		out := reflect.MakeMapWithSize(reflect.MapOf(v.Type().Key(), reflect.TypeFor[any]()), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			conv, err := c.convert(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			val := reflect.ValueOf(conv)
+			if !val.IsValid() {
+				val = reflect.Zero(reflect.TypeFor[any]())
+			}
+			out.SetMapIndex(iter.Key(), val)
+		}
+		return out.Interface(), nil
+	case reflect.Struct:
+		return c.convertStruct(v)
+	}
+	return v.Interface(), nil
+}
+
+func marshalsItself(v reflect.Value) bool {
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return true
+	}
+	if v.CanAddr() {
+		pt := reflect.PointerTo(t)
+		return pt.Implements(jsonMarshalerType) || pt.Implements(textMarshalerType)
+	}
+	return false
+}
+
+// convertStruct converts v's fields, in order, into a jsonObject.
+func (c *converter) convertStruct(v reflect.Value) (any, error) {
+	obj := jsonObject{}
+	for _, f := range cachedStructFields(v.Type()) {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok {
+			continue
+		}
+		if hasOption(f.opts, "omitempty") && isEmptyValue(fv) {
+			continue
+		}
+		value, err := c.convert(fv)
+		if err != nil {
+			return nil, err
+		}
+		if hasOption(f.opts, "string") {
+			switch fv.Kind() {
+			case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+				reflect.Float32, reflect.Float64, reflect.String:
+				b, _ := json.Marshal(fv.Interface())
+				value = string(b)
+			}
+		}
+		obj = append(obj, jsonField{f.name, value})
+	}
+	return obj, nil
+}
+
+// fieldByIndex walks index like reflect.Value.FieldByIndex, reporting false
+// when it passes a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// structField is a field encoding/json would encode, with the index path
+// to it through embedded structs.
+type structField struct {
+	name, opts string
+	index      []int
+	tagged     bool
+}
+
+var structFieldsCache sync.Map // reflect.Type -> []structField
+
+func cachedStructFields(t reflect.Type) []structField {
+	if f, ok := structFieldsCache.Load(t); ok {
+		return f.([]structField)
+	}
+	f, _ := structFieldsCache.LoadOrStore(t, structFields(t))
+	return f.([]structField)
+}
+
+// structFields lists t's fields the way encoding/json names them, in
+// declaration order. Fields of embedded structs are promoted unless a
+// shallower field has the same name; of several with one name at the same
+// depth, a single tagged one wins, otherwise all of them are dropped.
+func structFields(t reflect.Type) []structField {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	var fields []structField
+	taken := map[string]bool{}
+	visited := map[reflect.Type]bool{}
+	for next := []embedded{{typ: t}}; len(next) > 0; {
+		current := next
+		next = nil
+		byName := map[string][]structField{}
+		var order []string
+		for _, e := range current {
+			// a type embedded twice at one depth conflicts with itself,
+			// so only earlier depths count as visited
+			if visited[e.typ] {
+				continue
+			}
+			for i := range e.typ.NumField() {
+				sf := e.typ.Field(i)
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				index := append(slices.Clone(e.index), i)
+				if sf.Anonymous {
+					ft := sf.Type
+					if ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+					if name == "" && ft.Kind() == reflect.Struct {
+						next = append(next, embedded{ft, index})
+						continue
+					}
+				}
+				if !sf.IsExported() {
+					continue
+				}
+				tagged := name != ""
+				if !tagged {
+					name = sf.Name
+				}
+				if taken[name] {
+					continue
+				}
+				if byName[name] == nil {
+					order = append(order, name)
+				}
+				byName[name] = append(byName[name], structField{name, opts, index, tagged})
+			}
+		}
+		for _, e := range current {
+			visited[e.typ] = true
+		}
+		for _, name := range order {
+			taken[name] = true
+			candidates := byName[name]
+			if len(candidates) > 1 {
+				candidates = slices.DeleteFunc(candidates, func(f structField) bool { return !f.tagged })
+			}
+			if len(candidates) == 1 {
+				fields = append(fields, candidates[0])
+			}
+		}
+	}
+	slices.SortFunc(fields, func(a, b structField) int { return slices.Compare(a.index, b.index) })
+	return fields
+}
+
+func hasOption(opts, name string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// jsonObject is a JSON object that keeps its field order.
+type jsonObject []jsonField
+
+type jsonField struct {
+	key   string
+	value any
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		b, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// jsonStreamFlush is how many bytes JSONStream writes between flushes.
+const jsonStreamFlush = 32 << 10
+
+// JSONStream writes v as JSON straight to the connection, for payloads too
+// large to buffer. Slices, arrays and channels are written one element at
+// a time, flushing about every 32 KiB; a channel is read until closed.
+// Encoding stops when the client goes away.
+//
+// Unlike JSON, the status and headers are sent before encoding starts, so
+// an encoding error can't become an error response: it is returned and
+// the client gets truncated JSON. For the same reason, Steps that change
+// headers or status after the handler (ETags, BufferResponse rewrites,
+// response validation) don't work with it.
+func (f *FlowContext) JSONStream(status int, v any) error {
+	encode := func(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) }
+	if f.flow != nil && f.flow.jsonEncoder != nil {
+		encode = f.flow.jsonEncoder
+	}
+	f.Response.Header().Set("Content-Type", "application/json")
+	f.Response.WriteHeader(status)
+	w := &flushWriter{w: f.Response, rc: http.NewResponseController(f.Response)}
+	defer w.flush()
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 { // []byte, json.RawMessage
+			return encode(w, v)
+		}
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return encode(w, nil)
+		}
+		return f.streamElements(w, encode, func(i int) (any, bool) {
+			if i >= rv.Len() {
+				return nil, false
+			}
+			return rv.Index(i).Interface(), true
+		})
+	case reflect.Chan:
+		return f.streamElements(w, encode, func(int) (any, bool) {
+			elem, ok := rv.Recv()
+			if !ok {
+				return nil, false
+			}
+			return elem.Interface(), true
+		})
+	default:
+		return encode(w, v)
+	}
+}
+
+// streamElements writes a JSON array of the values next yields.
+func (f *FlowContext) streamElements(w *flushWriter, encode JSONEncoder, next func(i int) (any, bool)) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i := 0; ; i++ {
+		elem, ok := next(i)
+		if !ok {
+			break
+		}
+		if err := f.Request.Context().Err(); err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if err := encode(w, elem); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "]\n")
+	return err
+}
+
+// flushWriter flushes the response every jsonStreamFlush bytes.
+type flushWriter struct {
+	w       io.Writer
+	rc      *http.ResponseController
+	pending int
+}
+
+func (w *flushWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.pending += n
+	if err == nil && w.pending >= jsonStreamFlush {
+		w.flush()
+	}
+	return n, err
+}
+
+func (w *flushWriter) flush() {
+	w.pending = 0
+	_ = w.rc.Flush()
+}
@@ -0,0 +1,271 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JWTClaimsKey is the ctx key under which the JWT Step stores verified claims.
+const JWTClaimsKey = "jwt.claims"
+
+// JWTClaims holds the decoded payload of a verified token.
+type JWTClaims map[string]any
+
+// String returns a string claim (empty string if missing or not a string).
+func (c JWTClaims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Subject returns the "sub" claim.
+func (c JWTClaims) Subject() string { return c.String("sub") }
+
+// Audience returns the "aud" claim, which may be a string or a list.
+func (c JWTClaims) Audience() []string {
+	switch v := c["aud"].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// JWTConfig configures the JWT Step.
+type JWTConfig struct {
+	// Key verifies signatures: []byte for HS*, *rsa.PublicKey for RS*/PS*,
+	// *ecdsa.PublicKey for ES*.
+	Key any
+	// KeyFunc resolves the key per token (e.g. by "kid"); it takes precedence over Key.
+	KeyFunc func(header map[string]any) (any, error)
+	// Algorithms restricts accepted "alg" values. When empty, any algorithm
+	// matching the key type is accepted ("none" never is).
+	Algorithms []string
+	// Audience and Issuer, when set, must match the "aud" and "iss" claims.
+	Audience string
+	Issuer   string
+	// Leeway tolerates clock skew when checking exp/nbf/iat.
+	Leeway time.Duration
+}
+
+// JWT returns a Step that validates "Authorization: Bearer <token>" and
+// stores the claims in the context. Invalid or missing tokens get a 401
+// with a fixed message; why a token was rejected is logged at debug level.
+func JWT(cfg JWTConfig) Step {
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		token, ok := bearerToken(ctx.Request.Header.Get("Authorization"))
+		if !ok {
+			jwtUnauthorized(ctx, "missing bearer token")
+			return
+		}
+		claims, err := cfg.parse(token)
+		if err != nil {
+			// the detail may describe key lookup; keep it out of the response
+			ctx.flow.logger().Debug("flowhttp: rejected JWT", "path", ctx.Request.URL.Path, "error", err)
+			jwtUnauthorized(ctx, "invalid token")
+			return
+		}
+		ctx.Set(JWTClaimsKey, claims)
+		next(ctx)
+	})
+}
+
+// Claims returns the claims stored by the JWT Step (nil if none).
+func (f *FlowContext) Claims() JWTClaims {
+	c, _ := f.Get(JWTClaimsKey).(JWTClaims)
+	return c
+}
+
+// bearerToken extracts the token of a Bearer Authorization header; the
+// scheme name is case-insensitive.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+func jwtUnauthorized(ctx *FlowContext, msg string) {
+	ctx.Response.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	ctx.fail(http.StatusUnauthorized, msg)
+}
+
+// parse verifies the signature and registered claims of a compact JWS.
+func (cfg *JWTConfig) parse(token string) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header map[string]any
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.New("malformed token header")
+	}
+	alg, _ := header["alg"].(string)
+	if alg == "" || alg == "none" {
+		return nil, errors.New("unsupported token algorithm")
+	}
+	if len(cfg.Algorithms) > 0 && !slices.Contains(cfg.Algorithms, alg) {
+		return nil, fmt.Errorf("token algorithm %s not allowed", alg)
+	}
+
+	key := cfg.Key
+	if cfg.KeyFunc != nil {
+		k, err := cfg.KeyFunc(header)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed token signature")
+	}
+	if err := verifyJWS(alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims JWTClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.New("malformed token claims")
+	}
+	if err := cfg.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validate checks time-based claims and the configured audience/issuer.
+func (cfg *JWTConfig) validate(c JWTClaims) error {
+	now := time.Now()
+	if exp, ok := c["exp"].(float64); ok && now.After(unixTime(exp).Add(cfg.Leeway)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := c["nbf"].(float64); ok && now.Add(cfg.Leeway).Before(unixTime(nbf)) {
+		return errors.New("token not valid yet")
+	}
+	if iat, ok := c["iat"].(float64); ok && now.Add(cfg.Leeway).Before(unixTime(iat)) {
+		return errors.New("token issued in the future")
+	}
+	if cfg.Issuer != "" && c.String("iss") != cfg.Issuer {
+		return errors.New("invalid token issuer")
+	}
+	if cfg.Audience != "" && !slices.Contains(c.Audience(), cfg.Audience) {
+		return errors.New("invalid token audience")
+	}
+	return nil
+}
+
+// verifyJWS checks sig over signingInput; the key type must match the alg family.
+func verifyJWS(alg string, key any, signingInput string, sig []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported token algorithm %s", alg)
+	}
+	var hf crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hf = crypto.SHA256
+	case "384":
+		hf = crypto.SHA384
+	case "512":
+		hf = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported token algorithm %s", alg)
+	}
+	invalid := errors.New("invalid token signature")
+
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return invalid
+		}
+		mac := hmac.New(hashFunc(hf), secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return invalid
+		}
+		return nil
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return invalid
+		}
+		h := hf.New()
+		h.Write([]byte(signingInput))
+		if alg[0] == 'R' {
+			err := rsa.VerifyPKCS1v15(pub, hf, h.Sum(nil), sig)
+			if err != nil {
+				return invalid
+			}
+			return nil
+		}
+		if err := rsa.VerifyPSS(pub, hf, h.Sum(nil), sig, nil); err != nil {
+			return invalid
+		}
+		return nil
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return invalid
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return invalid
+		}
+		h := hf.New()
+		h.Write([]byte(signingInput))
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, h.Sum(nil), r, s) {
+			return invalid
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported token algorithm %s", alg)
+}
+
+func hashFunc(h crypto.Hash) func() hash.Hash {
+	switch h {
+	case crypto.SHA384:
+		return sha512.New384
+	case crypto.SHA512:
+		return sha512.New
+	}
+	return sha256.New
+}
+
+func decodeSegment(seg string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func unixTime(sec float64) time.Time {
+	return time.Unix(int64(sec), 0)
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var jwtSecret = []byte("test-secret")
+
+// signJWT builds a compact JWS over claims; sign receives the signing input.
+func signJWT(t *testing.T, alg string, claims map[string]any, sign func(input []byte) []byte) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]any{"alg": alg, "typ": "JWT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(input)))
+}
+
+func hs256(key []byte) func([]byte) []byte {
+	return func(input []byte) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(input)
+		return mac.Sum(nil)
+	}
+}
+
+func TestJWT(t *testing.T) {
+	now := time.Now().Unix()
+	valid := signJWT(t, "HS256", map[string]any{"sub": "ada", "iss": "me", "aud": []string{"api"}, "exp": now + 60}, hs256(jwtSecret))
+	tests := []struct {
+		name   string
+		auth   string
+		status int
+	}{
+		{name: "valid", auth: "Bearer " + valid, status: http.StatusOK},
+		{name: "lowercase scheme", auth: "bearer " + valid, status: http.StatusOK},
+		{name: "uppercase scheme", auth: "BEARER " + valid, status: http.StatusOK},
+		{name: "extra spaces", auth: "  Bearer   " + valid + " ", status: http.StatusOK},
+		{name: "missing", status: http.StatusUnauthorized},
+		{name: "other scheme", auth: "Basic " + valid, status: http.StatusUnauthorized},
+		{name: "no token", auth: "Bearer ", status: http.StatusUnauthorized},
+		{name: "malformed", auth: "Bearer abc.def", status: http.StatusUnauthorized},
+		{name: "wrong key", status: http.StatusUnauthorized,
+			auth: "Bearer " + signJWT(t, "HS256", map[string]any{"sub": "ada", "iss": "me", "aud": "api"}, hs256([]byte("other")))},
+		{name: "alg none", status: http.StatusUnauthorized,
+			auth: "Bearer " + signJWT(t, "none", map[string]any{"sub": "ada", "iss": "me", "aud": "api"}, func([]byte) []byte { return nil })},
+		{name: "alg not allowed", status: http.StatusUnauthorized,
+			auth: "Bearer " + signJWT(t, "HS512", map[string]any{"sub": "ada", "iss": "me", "aud": "api"}, hs256(jwtSecret))},
+		{name: "expired", status: http.StatusUnauthorized,
+			auth: "Bearer " + signJWT(t, "HS256", map[string]any{"sub": "ada", "iss": "me", "aud": "api", "exp": now - 60}, hs256(jwtSecret))},
+		{name: "expired within leeway", status: http.StatusOK,
+			auth: "Bearer " + signJWT(t, "HS256", map[string]any{"sub": "ada", "iss": "me", "aud": "api", "exp": now - 5}, hs256(jwtSecret))},
+		{name: "not yet valid", status: http.StatusUnauthorized,
+			auth: "Bearer " + signJWT(t, "HS256", map[string]any{"sub": "ada", "iss": "me", "aud": "api", "nbf": now + 60}, hs256(jwtSecret))},
+		{name: "wrong issuer", status: http.StatusUnauthorized,
+			auth: "Bearer " + signJWT(t, "HS256", map[string]any{"sub": "ada", "iss": "you", "aud": "api"}, hs256(jwtSecret))},
+		{name: "wrong audience", status: http.StatusUnauthorized,
+			auth: "Bearer " + signJWT(t, "HS256", map[string]any{"sub": "ada", "iss": "me", "aud": "web"}, hs256(jwtSecret))},
+	}
+
+	f := NewFlow()
+	cfg := JWTConfig{Key: jwtSecret, Algorithms: []string{"HS256"}, Issuer: "me", Audience: "api", Leeway: 10 * time.Second}
+	f.Stream("GET", "/me", []Step{JWT(cfg)}, func(ctx *FlowContext) {
+		ctx.Response.Write([]byte(ctx.Claims().Subject()))
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/me", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := f.Test(req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusOK {
+				if got := rec.Body.String(); got != "ada" {
+					t.Errorf("body = %q, want ada", got)
+				}
+				return
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != `Bearer error="invalid_token"` {
+				t.Errorf("WWW-Authenticate = %q", got)
+			}
+		})
+	}
+}
+
+func TestJWTKeyTypes(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]any{"sub": "ada"}
+	rs256 := signJWT(t, "RS256", claims, func(input []byte) []byte {
+		sum := sha256.Sum256(input)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	})
+	ps256 := signJWT(t, "PS256", claims, func(input []byte) []byte {
+		sum := sha256.Sum256(input)
+		sig, err := rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, sum[:], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	})
+	es256 := signJWT(t, "ES256", claims, func(input []byte) []byte {
+		sum := sha256.Sum256(input)
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig
+	})
+	hs := signJWT(t, "HS256", claims, hs256(jwtSecret))
+
+	tests := []struct {
+		name  string
+		token string
+		key   any
+		ok    bool
+	}{
+		{"RS256", rs256, &rsaKey.PublicKey, true},
+		{"PS256", ps256, &rsaKey.PublicKey, true},
+		{"ES256", es256, &ecKey.PublicKey, true},
+		{"HS256", hs, jwtSecret, true},
+		// a public key must not be usable as an HMAC secret
+		{"HS256 with RSA key", hs, &rsaKey.PublicKey, false},
+		{"RS256 with EC key", rs256, &ecKey.PublicKey, false},
+		{"ES256 with secret", es256, jwtSecret, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := JWTConfig{Key: tt.key}
+			claims, err := cfg.parse(tt.token)
+			if ok := err == nil; ok != tt.ok {
+				t.Fatalf("parse error = %v, want ok = %v", err, tt.ok)
+			}
+			if tt.ok && claims.Subject() != "ada" {
+				t.Errorf("sub = %q, want ada", claims.Subject())
+			}
+		})
+	}
+}
+
+func TestJWTKeyFunc(t *testing.T) {
+	keys := map[string][]byte{"a": []byte("key-a"), "b": []byte("key-b")}
+	cfg := JWTConfig{KeyFunc: func(header map[string]any) (any, error) {
+		kid, _ := header["kid"].(string)
+		return keys[kid], nil
+	}}
+	sign := func(kid string, key []byte) string {
+		header, _ := json.Marshal(map[string]any{"alg": "HS256", "kid": kid})
+		input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"ada"}`))
+		return input + "." + base64.RawURLEncoding.EncodeToString(hs256(key)([]byte(input)))
+	}
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"kid a", sign("a", keys["a"]), true},
+		{"kid b", sign("b", keys["b"]), true},
+		{"kid mismatch", sign("a", keys["b"]), false},
+		{"unknown kid", sign("c", keys["a"]), false},
+	}
+	for _, tt := range tests {
+		if _, err := cfg.parse(tt.token); (err == nil) != tt.ok {
+			t.Errorf("%s: parse error = %v, want ok = %v", tt.name, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"time"
+)
+
+// OnShutdown registers fn to run once the server has stopped, e.g. to close
+// DB pools or flush logs. Hooks run in registration order with a context
+// bounded by the shutdown timeout.
+func (f *Flow) OnShutdown(fn func(ctx context.Context)) {
+	f.onShutdown = append(f.onShutdown, fn)
+}
+
+// OnStart registers fn to run when the server has bound its listener, with
+// the actual address (useful with port 0), e.g. for warmup or
+// service-discovery registration. Hooks run before requests are served.
+func (f *Flow) OnStart(fn func(addr string)) {
+	f.onStart = append(f.onStart, fn)
+}
+
+// OnStop registers fn to run after the server has stopped for any reason,
+// including serve errors, after any OnShutdown hooks.
+func (f *Flow) OnStop(fn func()) {
+	f.onStop = append(f.onStop, fn)
+}
+
+// Draining returns a channel closed when the server starts shutting down, so
+// long-lived handlers can say goodbye and return. It is nil (never closed)
+// outside a running Flow.
+func (f *FlowContext) Draining() <-chan struct{} {
+	if f.flow == nil {
+		return nil
+	}
+	f.flow.mu.Lock()
+	defer f.flow.mu.Unlock()
+	return f.flow.draining
+}
+
+// LongLived marks the request as a long-lived connection (SSE, a hijacked
+// WebSocket) that Shutdown should wait for, up to the drain timeout.
+// Call the returned func when the connection ends.
+func (f *FlowContext) LongLived() (done func()) {
+	if f.flow == nil {
+		return func() {}
+	}
+	f.flow.longLived.Add(1)
+	return f.flow.longLived.Done
+}
+
+func (f *Flow) shutdownWait() time.Duration {
+	if f.shutdownTimeout > 0 {
+		return f.shutdownTimeout
+	}
+	return 5 * time.Second
+}
+
+func (f *Flow) waitLongLived() {
+	if f.drainTimeout <= 0 {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		f.longLived.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(f.drainTimeout):
+	}
+}
+
+func (f *Flow) runShutdownHooks() {
+	ctx, cancel := context.WithTimeout(context.Background(), f.shutdownWait())
+	defer cancel()
+	for _, fn := range f.onShutdown {
+		fn(ctx)
+	}
+}
+
+func (f *Flow) runStopHooks() {
+	for _, fn := range f.onStop {
+		fn()
+	}
+}
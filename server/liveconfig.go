@@ -0,0 +1,320 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RuntimeConfig holds the settings that can change without a restart.
+type RuntimeConfig struct {
+	RateLimit RateLimitConfig `json:"rate_limit"`
+	// CORSOrigins lists the origins allowed by LiveConfig.CORS; "*" allows any.
+	CORSOrigins []string `json:"cors_origins"`
+	// CORSMethods lists the methods preflights allow (defaults to GET,
+	// HEAD and POST).
+	CORSMethods []string `json:"cors_methods,omitempty"`
+	// CORSHeaders lists the request headers preflights allow (defaults to
+	// those the preflight asks for).
+	CORSHeaders []string `json:"cors_headers,omitempty"`
+	// CORSCredentials lets browsers send cookies and credentials.
+	CORSCredentials bool `json:"cors_credentials,omitempty"`
+	// CORSMaxAge is how long, in seconds, browsers may cache a preflight.
+	CORSMaxAge int `json:"cors_max_age,omitempty"`
+	// Maintenance makes LiveConfig.Maintenance answer 503.
+	Maintenance bool `json:"maintenance"`
+	// MaintenanceMessage is sent with the 503 (defaults to the status text).
+	MaintenanceMessage string `json:"maintenance_message,omitempty"`
+}
+
+// RateLimitConfig allows Requests per Window for each client. Zero
+// Requests disables limiting. In JSON, Window is a duration string ("1m").
+type RateLimitConfig struct {
+	Requests int           `json:"requests"`
+	Window   time.Duration `json:"window"`
+}
+
+func (r RateLimitConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Requests int    `json:"requests"`
+		Window   string `json:"window"`
+	}{r.Requests, r.Window.String()})
+}
+
+func (r *RateLimitConfig) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Requests *int            `json:"requests"`
+		Window   json.RawMessage `json:"window"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Requests != nil {
+		r.Requests = *raw.Requests
+	}
+	if len(raw.Window) == 0 {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(raw.Window, &s); err == nil {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid rate limit window: %v", err)
+		}
+		r.Window = d
+		return nil
+	}
+	var ns int64
+	if err := json.Unmarshal(raw.Window, &ns); err != nil {
+		return fmt.Errorf("invalid rate limit window: %s", raw.Window)
+	}
+	r.Window = time.Duration(ns)
+	return nil
+}
+
+// LiveConfig holds a RuntimeConfig that is swapped atomically, so Steps
+// built from it see every update on their next request. Update it in code,
+// through the endpoint added by Mount, or from a file with WatchFile.
+type LiveConfig struct {
+	v  atomic.Pointer[RuntimeConfig]
+	mu sync.Mutex // serializes writers
+}
+
+// NewLiveConfig returns a LiveConfig starting at initial.
+func NewLiveConfig(initial RuntimeConfig) *LiveConfig {
+	c := &LiveConfig{}
+	c.v.Store(&initial)
+	return c
+}
+
+// Load returns the current configuration. It must not be modified.
+func (c *LiveConfig) Load() *RuntimeConfig { return c.v.Load() }
+
+// Store replaces the configuration.
+func (c *LiveConfig) Store(cfg RuntimeConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.v.Store(&cfg)
+}
+
+// Update applies fn to a copy of the configuration and stores the result.
+func (c *LiveConfig) Update(fn func(cfg *RuntimeConfig)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cfg := *c.v.Load()
+	cfg.CORSOrigins = slices.Clone(cfg.CORSOrigins)
+	cfg.CORSMethods = slices.Clone(cfg.CORSMethods)
+	cfg.CORSHeaders = slices.Clone(cfg.CORSHeaders)
+	fn(&cfg)
+	c.v.Store(&cfg)
+}
+
+// Mount registers GET path, returning the configuration as JSON, and POST
+// path, merging the JSON body into it (fields left out keep their value).
+// Protect it with steps, e.g. an auth Step.
+func (c *LiveConfig) Mount(b *Branch, path string, steps ...Step) {
+	b.Stream("GET", path, steps, func(ctx *FlowContext) {
+		ctx.JSON(http.StatusOK, c.Load())
+	})
+	b.Stream("POST", path, steps, func(ctx *FlowContext) {
+		body, err := ctx.RawBody()
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		var decodeErr error
+		c.Update(func(cfg *RuntimeConfig) {
+			next := *cfg
+			if decodeErr = json.Unmarshal(body, &next); decodeErr == nil {
+				*cfg = next
+			}
+		})
+		if decodeErr != nil {
+			ctx.fail(http.StatusBadRequest, "invalid configuration: "+decodeErr.Error())
+			return
+		}
+		ctx.JSON(http.StatusOK, c.Load())
+	})
+}
+
+// WatchFile loads the JSON file at path and reloads it whenever its
+// modification time changes, polling every interval (defaults to 5s).
+// Unreadable or invalid versions are logged and keep the previous
+// configuration. Call stop to end the watch, e.g. from f.OnStop.
+func (c *LiveConfig) WatchFile(path string, interval time.Duration) (stop func(), err error) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	modTime, err := c.loadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			st, err := os.Stat(path)
+			if err != nil || st.ModTime().Equal(modTime) {
+				continue
+			}
+			if mt, err := c.loadFile(path); err != nil {
+				slog.Default().Error("config reload failed", "path", path, "error", err)
+				modTime = st.ModTime()
+			} else {
+				modTime = mt
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }, nil
+}
+
+func (c *LiveConfig) loadFile(path string) (time.Time, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var cfg RuntimeConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return time.Time{}, fmt.Errorf("invalid configuration in %s: %v", path, err)
+	}
+	c.Store(cfg)
+	return st.ModTime(), nil
+}
+
+// Maintenance returns a Step answering 503 with a Retry-After header while
+// maintenance mode is on.
+func (c *LiveConfig) Maintenance() Step {
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		cfg := c.Load()
+		if !cfg.Maintenance {
+			next(ctx)
+			return
+		}
+		ctx.Response.Header().Set("Retry-After", "120")
+		ctx.fail(http.StatusServiceUnavailable, cfg.MaintenanceMessage)
+	})
+}
+
+// CORS returns a Step that allows cross-origin requests from the
+// configured origins by echoing them in Access-Control-Allow-Origin, and
+// answers their preflight OPTIONS requests with the configured methods,
+// headers and credentials policy. The router rejects OPTIONS before route
+// Steps run, so register it with WithPreRouting to serve preflights.
+func (c *LiveConfig) CORS() Step {
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		cfg := c.Load()
+		h := ctx.Response.Header()
+		h.Add("Vary", "Origin")
+		origin := ctx.Request.Header.Get("Origin")
+		allowed := origin != "" && (slices.Contains(cfg.CORSOrigins, "*") || slices.Contains(cfg.CORSOrigins, origin))
+		if allowed {
+			h.Set("Access-Control-Allow-Origin", origin)
+			if cfg.CORSCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		preflight := ctx.Request.Method == http.MethodOptions && ctx.Request.Header.Get("Access-Control-Request-Method") != ""
+		if !preflight {
+			next(ctx)
+			return
+		}
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		if allowed {
+			methods := cfg.CORSMethods
+			if len(methods) == 0 {
+				methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+			}
+			h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			if len(cfg.CORSHeaders) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(cfg.CORSHeaders, ", "))
+			} else if requested := ctx.Request.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				h.Set("Access-Control-Allow-Headers", requested)
+			}
+			if cfg.CORSMaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(cfg.CORSMaxAge))
+			}
+		}
+		ctx.Response.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// RateLimit returns a Step limiting each client, identified by key
+// (defaults to ctx.ClientIP), to the configured requests per window with
+// a fixed window. Rejected requests get a 429 with Retry-After. Limit
+// changes apply from each client's next window.
+func (c *LiveConfig) RateLimit(key func(*FlowContext) string) Step {
+	if key == nil {
+		key = func(ctx *FlowContext) string { return ctx.ClientIP() }
+	}
+	type window struct {
+		start time.Time
+		count int
+	}
+	var (
+		mu      sync.Mutex
+		windows = make(map[string]*window)
+		swept   time.Time
+	)
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		limit := c.Load().RateLimit
+		if limit.Requests <= 0 || limit.Window <= 0 {
+			next(ctx)
+			return
+		}
+		now := time.Now()
+		k := key(ctx)
+
+		mu.Lock()
+		if now.Sub(swept) > limit.Window {
+			for wk, w := range windows {
+				if now.Sub(w.start) >= limit.Window {
+					delete(windows, wk)
+				}
+			}
+			swept = now
+		}
+		w := windows[k]
+		if w == nil || now.Sub(w.start) >= limit.Window {
+			w = &window{start: now}
+			windows[k] = w
+		}
+		w.count++
+		count, reset := w.count, w.start.Add(limit.Window)
+		mu.Unlock()
+
+		h := ctx.Response.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(limit.Requests))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(max(limit.Requests-count, 0)))
+		if count > limit.Requests {
+			h.Set("Retry-After", strconv.Itoa(int(math.Ceil(reset.Sub(now).Seconds()))))
+			ctx.fail(http.StatusTooManyRequests, "")
+			return
+		}
+		next(ctx)
+	})
+}
@@ -1,5 +1,7 @@
 package server
 
+import "fmt"
+
 // Step is a middleware: it receives next Sink and returns a Sink.
 type Step func(Sink) Sink
 
@@ -9,3 +11,100 @@ func CreateStep(fn func(next Sink, ctx *FlowContext)) Step {
 		return func(ctx *FlowContext) { fn(next, ctx) }
 	}
 }
+
+// When applies step only to requests for which pred returns true;
+// other requests skip straight to the next Sink.
+func When(pred func(*FlowContext) bool, step Step) Step {
+	return func(next Sink) Sink {
+		wrapped := step(next)
+		return func(ctx *FlowContext) {
+			if pred(ctx) {
+				wrapped(ctx)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// Unless applies step except to requests for which pred returns true,
+// e.g. Unless(IsMethod("OPTIONS"), auth).
+func Unless(pred func(*FlowContext) bool, step Step) Step {
+	return When(func(ctx *FlowContext) bool { return !pred(ctx) }, step)
+}
+
+// IsMethod is a predicate for When/Unless matching any of the given methods.
+func IsMethod(methods ...string) func(*FlowContext) bool {
+	return func(ctx *FlowContext) bool {
+		for _, m := range methods {
+			if ctx.Request.Method == m {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RegisterStep stores step under name so it can be referenced with
+// f.Step/f.Steps (e.g. from config) and disabled per branch or route with Skip.
+func (f *Flow) RegisterStep(name string, step Step) {
+	if f.registry == nil {
+		f.registry = make(map[string]Step)
+	}
+	f.registry[name] = step
+}
+
+// Step returns the step registered under name, wrapped so that routes
+// skipping that name bypass it. It panics if no such step is registered.
+func (f *Flow) Step(name string) Step {
+	step, ok := f.registry[name]
+	if !ok {
+		panic(fmt.Errorf("no step registered as %q", name))
+	}
+	return func(next Sink) Sink {
+		if f.naming != nil {
+			*f.naming = append(*f.naming, name)
+		}
+		wrapped := step(next)
+		return func(ctx *FlowContext) {
+			if ctx.route != nil && ctx.route.Skips(name) {
+				next(ctx)
+				return
+			}
+			wrapped(ctx)
+		}
+	}
+}
+
+// routeStepNames returns the names of the steps among steps that came from
+// f.Step, in order. It builds the chain once, as each request does, with
+// f.naming set so those steps report their name; nothing runs.
+func (f *Flow) routeStepNames(steps []Step) []string {
+	var collected []string
+	f.naming = &collected
+	defer func() { f.naming = nil }()
+
+	// the chain is built from the last step, so collect each step's names
+	// separately to keep declaration order
+	perStep := make([][]string, len(steps))
+	sink := Sink(func(*FlowContext) {})
+	for i := len(steps) - 1; i >= 0; i-- {
+		start := len(collected)
+		sink = steps[i](sink)
+		perStep[i] = collected[start:]
+	}
+	names := []string{}
+	for _, n := range perStep {
+		names = append(names, n...)
+	}
+	return names
+}
+
+// Steps resolves several registered steps, in order, for Fork/Stream.
+func (f *Flow) Steps(names ...string) []Step {
+	steps := make([]Step, len(names))
+	for i, name := range names {
+		steps[i] = f.Step(name)
+	}
+	return steps
+}
@@ -0,0 +1,35 @@
+package server
+
+import "fmt"
+
+// Module is a reusable feature package (auth, admin UI, metrics, ...) that
+// registers its routes, Steps and lifecycle hooks on a Flow.
+type Module interface {
+	Register(f *Flow) error
+}
+
+// ModuleFunc adapts a function to Module.
+type ModuleFunc func(f *Flow) error
+
+func (fn ModuleFunc) Register(f *Flow) error { return fn(f) }
+
+// Use registers modules in order, stopping at the first that fails. Call
+// it before serving, like Stream.
+func (f *Flow) Use(modules ...Module) error {
+	for _, m := range modules {
+		if err := m.Register(f); err != nil {
+			return fmt.Errorf("register module %T: %w", m, err)
+		}
+		f.modules = append(f.modules, m)
+	}
+	return nil
+}
+
+// PreRouting adds steps run before the route lookup, like WithPreRouting,
+// for modules that observe every request. Call it before serving.
+func (f *Flow) PreRouting(steps ...Step) {
+	f.preRouting = append(f.preRouting, steps...)
+}
+
+// Modules returns the modules registered with Use.
+func (f *Flow) Modules() []Module { return f.modules }
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+const notifiersKey = "error.notifiers"
+
+// ErrorNotifier reports server errors to an error tracker (Sentry, Rollbar,
+// ...). It is called with recovered panics and with errors passed to
+// ctx.Error that result in a 5xx response; stack is the goroutine stack at
+// the panic or ctx.Error call.
+type ErrorNotifier interface {
+	Notify(ctx *FlowContext, err error, stack []byte)
+}
+
+// ErrorNotifierFunc adapts a function to ErrorNotifier.
+type ErrorNotifierFunc func(ctx *FlowContext, err error, stack []byte)
+
+func (fn ErrorNotifierFunc) Notify(ctx *FlowContext, err error, stack []byte) {
+	fn(ctx, err, stack)
+}
+
+// PanicError wraps a recovered panic value.
+type PanicError struct {
+	Value any
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("panic: %v", e.Value) }
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// WithErrorNotifier adds a notifier called for every route.
+func WithErrorNotifier(n ErrorNotifier) Option {
+	return func(f *Flow) { f.notifiers = append(f.notifiers, n) }
+}
+
+// NotifyErrors returns a Step adding n for the routes it is used on, in
+// addition to any WithErrorNotifier notifiers.
+func NotifyErrors(n ErrorNotifier) Step {
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		prev, _ := ctx.Get(notifiersKey).([]ErrorNotifier)
+		ctx.Set(notifiersKey, append(prev[:len(prev):len(prev)], n))
+		next(ctx)
+	})
+}
+
+// notify hands err to the global and route notifiers.
+func (f *FlowContext) notify(err error, stack []byte) {
+	var notifiers []ErrorNotifier
+	if f.flow != nil {
+		notifiers = f.flow.notifiers
+	}
+	local, _ := f.Get(notifiersKey).([]ErrorNotifier)
+	notifiers = append(notifiers[:len(notifiers):len(notifiers)], local...)
+	for _, n := range notifiers {
+		n.Notify(f, err, stack)
+	}
+}
+
+// isServerError reports whether err will be answered with a 5xx.
+func isServerError(err error) bool {
+	var he *HTTPError
+	return !errors.As(err, &he) || he.Code >= 500
+}
+
+// recoverPanic turns a panic in the chain into a notification and a 500.
+// http.ErrAbortHandler is re-panicked so net/http aborts the response.
+func (f *FlowContext) recoverPanic() {
+	p := recover()
+	if p == nil {
+		return
+	}
+	if p == http.ErrAbortHandler {
+		panic(p)
+	}
+	err := &PanicError{Value: p}
+	f.notify(err, debug.Stack())
+	f.handleError(err)
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+)
+
+// Option configures a Flow in NewFlow.
+type Option func(*Flow)
+
+// JSONEncoder writes v as JSON to w; see WithJSONEncoder.
+type JSONEncoder func(w io.Writer, v any) error
+
+// WithNotFound sets the Sink answering requests that match no route.
+func WithNotFound(sink Sink) Option {
+	return func(f *Flow) { f.notFound = sink }
+}
+
+// WithErrorHandler sets the handler behind ctx.Error, which every built-in
+// Step uses to reject requests. See DefaultErrorHandler.
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(f *Flow) { f.errorHandler = h }
+}
+
+// WithJSONEncoder replaces encoding/json for ctx.JSON, e.g. with a faster
+// encoder or one with custom conventions.
+func WithJSONEncoder(enc JSONEncoder) Option {
+	return func(f *Flow) { f.jsonEncoder = enc }
+}
+
+// WithTrustedProxies lists proxy addresses or CIDR ranges ("10.0.0.0/8",
+// "127.0.0.1") whose forwarding headers ctx.ClientIP may believe.
+// It panics on malformed entries.
+func WithTrustedProxies(proxies ...string) Option {
+	return func(f *Flow) {
+		for _, p := range proxies {
+			if !strings.Contains(p, "/") {
+				addr, err := netip.ParseAddr(p)
+				if err != nil {
+					panic(fmt.Errorf("invalid trusted proxy %q: %v", p, err))
+				}
+				f.trustedProxies = append(f.trustedProxies, netip.PrefixFrom(addr, addr.BitLen()))
+				continue
+			}
+			prefix, err := netip.ParsePrefix(p)
+			if err != nil {
+				panic(fmt.Errorf("invalid trusted proxy %q: %v", p, err))
+			}
+			f.trustedProxies = append(f.trustedProxies, prefix.Masked())
+		}
+	}
+}
+
+// WithPreRouting runs steps on every request before the route lookup, so
+// they can change what is routed, e.g. StripPrefix and RewritePath, or
+// act on unmatched requests too. They share the routed request's
+// FlowContext: ctx.Route() is nil until next returns, and values they Set
+// and funcs they Defer carry over.
+func WithPreRouting(steps ...Step) Option {
+	return func(f *Flow) { f.PreRouting(steps...) }
+}
+
+// WithMaxBodySize limits request bodies to n bytes for every route without
+// its own Route.MaxBody. See Route.MaxBody.
+func WithMaxBodySize(n int64) Option {
+	return func(f *Flow) { f.maxBody = n }
+}
+
+// WithBodyBuffering buffers every routed request body up to limit bytes,
+// like the BufferBody Step, so any Step may re-read it.
+func WithBodyBuffering(limit int64) Option {
+	return func(f *Flow) { f.bodyBuffer = limit }
+}
+
+// WithLogger sets the logger used for internal errors (defaults to slog.Default()).
+func WithLogger(l *slog.Logger) Option {
+	return func(f *Flow) { f.log = l }
+}
+
+// WithTLSConfig sets the TLS configuration used by RunTLS, e.g. to raise
+// MinVersion, restrict CipherSuites, or require client certificates (mTLS)
+// via ClientCAs and ClientAuth.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(f *Flow) { f.tlsConfig = cfg }
+}
+
+// WithServerConfig sets timeouts and limits for servers started by Run,
+// RunTLS, RunContext, RunUnix and Serve.
+func WithServerConfig(cfg ServerConfig) Option {
+	return func(f *Flow) { f.serverConfig = cfg }
+}
+
+// WithSignals sets the signals that make Run, RunTLS, RunUnix and Serve shut
+// down gracefully (defaults to os.Interrupt and SIGTERM). With no signals,
+// signal handling is disabled, e.g. when the embedding program owns it.
+func WithSignals(sigs ...os.Signal) Option {
+	return func(f *Flow) { f.signals = sigs }
+}
+
+// WithShutdownTimeout sets how long a signal- or context-triggered shutdown
+// waits for active requests (defaults to 5s).
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(f *Flow) { f.shutdownTimeout = d }
+}
+
+// WithDrainTimeout sets how long Shutdown additionally waits for connections
+// registered with ctx.LongLived (SSE, WebSockets) to finish. Zero, the
+// default, doesn't wait.
+func WithDrainTimeout(d time.Duration) Option {
+	return func(f *Flow) { f.drainTimeout = d }
+}
+
+// logger returns the configured logger or slog.Default().
+func (f *Flow) logger() *slog.Logger {
+	if f != nil && f.log != nil {
+		return f.log
+	}
+	return slog.Default()
+}
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// ClientIP returns the client's IP address. When the direct peer is a
+// trusted proxy (see WithTrustedProxies), the Forwarded header (RFC 7239)
+// or else X-Forwarded-For is walked from the right, skipping trusted hops,
+// and X-Real-IP is the fallback; otherwise the forwarding headers are
+// ignored as spoofable.
+func (f *FlowContext) ClientIP() string {
+	peer := remoteAddr(f.Request.RemoteAddr)
+	if !peer.IsValid() {
+		return f.Request.RemoteAddr
+	}
+	if f.flow == nil || !f.flow.trusted(peer) {
+		return peer.String()
+	}
+
+	if elems := parseForwarded(f.Request.Header.Values("Forwarded")); len(elems) > 0 {
+		if addr, ok := f.flow.forwardedClient(elems); ok {
+			return addr.String()
+		}
+	}
+	if hops := headerList(f.Request.Header.Values("X-Forwarded-For")); len(hops) > 0 {
+		if addr, err := netip.ParseAddr(hops[len(hops)-1-f.forwardedHop()]); err == nil {
+			if addr = addr.Unmap(); !f.flow.trusted(addr) {
+				return addr.String()
+			}
+		}
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSpace(f.Request.Header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap().String()
+	}
+	return peer.String()
+}
+
+// Scheme returns the scheme ("http" or "https") the client used. Behind a
+// trusted proxy it is taken from the Forwarded header's proto or else
+// X-Forwarded-Proto, from the same hop as ClientIP; otherwise from the
+// connection.
+func (f *FlowContext) Scheme() string {
+	if f.behindTrustedProxy() {
+		if elem, ok := f.forwardedElement(); ok && elem.proto != "" {
+			return strings.ToLower(elem.proto)
+		}
+		if proto := f.forwardedValue("X-Forwarded-Proto"); proto != "" {
+			return strings.ToLower(proto)
+		}
+	}
+	if f.Request.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Host returns the host (and port, if any) the client addressed. Behind a
+// trusted proxy it is taken from the Forwarded header's host or else
+// X-Forwarded-Host, from the same hop as ClientIP; otherwise it is the
+// request's Host.
+func (f *FlowContext) Host() string {
+	if f.behindTrustedProxy() {
+		if elem, ok := f.forwardedElement(); ok && elem.host != "" {
+			return elem.host
+		}
+		if host := f.forwardedValue("X-Forwarded-Host"); host != "" {
+			return host
+		}
+	}
+	return f.Request.Host
+}
+
+// BaseURL returns the public base URL of the app, e.g.
+// "https://example.com/myapp": Scheme and Host plus the prefix removed by
+// StripPrefix, without a trailing slash.
+func (f *FlowContext) BaseURL() string {
+	prefix, _ := f.Get(PathPrefixKey).(string)
+	return f.Scheme() + "://" + f.Host() + prefix
+}
+
+// AbsoluteURL resolves path, which may carry a query, against BaseURL, for
+// links in emails or Location headers: ctx.AbsoluteURL("/orders/7") gives
+// "https://example.com/myapp/orders/7". Absolute URLs are returned as is.
+func (f *FlowContext) AbsoluteURL(path string) string {
+	if u, err := url.Parse(path); err == nil && u.IsAbs() {
+		return path
+	}
+	return f.BaseURL() + "/" + strings.TrimPrefix(path, "/")
+}
+
+func (f *FlowContext) behindTrustedProxy() bool {
+	if f.flow == nil {
+		return false
+	}
+	peer := remoteAddr(f.Request.RemoteAddr)
+	return peer.IsValid() && f.flow.trusted(peer)
+}
+
+// forwardedElement returns the Forwarded element added by the outermost
+// trusted proxy: the one describing the first untrusted hop from the
+// right, or the leftmost if every hop is trusted.
+func (f *FlowContext) forwardedElement() (forwardedElem, bool) {
+	elems := parseForwarded(f.Request.Header.Values("Forwarded"))
+	if len(elems) == 0 {
+		return forwardedElem{}, false
+	}
+	for i := len(elems) - 1; i >= 0; i-- {
+		addr, ok := forwardedAddr(elems[i].forNode)
+		if !ok || !f.flow.trusted(addr) {
+			return elems[i], true
+		}
+	}
+	return elems[0], true
+}
+
+// forwardedClient walks Forwarded elements from the right like
+// X-Forwarded-For, returning the first untrusted address.
+func (f *Flow) forwardedClient(elems []forwardedElem) (netip.Addr, bool) {
+	for i := len(elems) - 1; i >= 0; i-- {
+		addr, ok := forwardedAddr(elems[i].forNode)
+		if !ok {
+			return netip.Addr{}, false
+		}
+		if !f.trusted(addr) {
+			return addr, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// forwardedElem is one comma-separated element of a Forwarded header.
+type forwardedElem struct {
+	forNode, proto, host string
+}
+
+// parseForwarded parses Forwarded header values, e.g.
+// `for=192.0.2.60;proto=https;host=example.com, for="[2001:db8::1]:4711"`.
+// Parameter names are case-insensitive and values may be quoted.
+func parseForwarded(values []string) []forwardedElem {
+	var elems []forwardedElem
+	for _, v := range values {
+		for _, part := range splitQuoted(v, ',') {
+			var elem forwardedElem
+			for _, pair := range splitQuoted(part, ';') {
+				name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				value = strings.TrimSpace(value)
+				if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+					value = strings.ReplaceAll(value[1:len(value)-1], `\`, "")
+				}
+				switch strings.ToLower(strings.TrimSpace(name)) {
+				case "for":
+					elem.forNode = value
+				case "proto":
+					elem.proto = value
+				case "host":
+					elem.host = value
+				}
+			}
+			elems = append(elems, elem)
+		}
+	}
+	return elems
+}
+
+// splitQuoted splits s at sep outside double-quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// forwardedAddr parses a Forwarded "for" node: "192.0.2.60",
+// "192.0.2.60:8080" or "[2001:db8::1]:4711". "unknown" and obfuscated
+// identifiers ("_hidden") aren't addresses.
+func forwardedAddr(node string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(node); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(node, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// forwardedHop returns the position, counted from the right, of the
+// X-Forwarded-For hop describing the client: the first that isn't a
+// trusted proxy (or isn't an address), or the leftmost if all are trusted.
+// It is 0 without the header, i.e. the nearest proxy's view.
+func (f *FlowContext) forwardedHop() int {
+	hops := headerList(f.Request.Header.Values("X-Forwarded-For"))
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(hops[i])
+		if err != nil || !f.flow.trusted(addr.Unmap()) {
+			return len(hops) - 1 - i
+		}
+	}
+	return max(len(hops)-1, 0)
+}
+
+// forwardedValue returns the value of an X-Forwarded-* list header that
+// the proxy which recorded the client's X-Forwarded-For hop appended,
+// aligning both lists from the right. Proxies appending only to
+// X-Forwarded-For leave the list shorter; its leftmost value is used then.
+func (f *FlowContext) forwardedValue(name string) string {
+	values := headerList(f.Request.Header.Values(name))
+	if len(values) == 0 {
+		return ""
+	}
+	return values[max(len(values)-1-f.forwardedHop(), 0)]
+}
+
+// headerList splits comma-separated header values into trimmed items.
+func headerList(values []string) []string {
+	var items []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			items = append(items, strings.TrimSpace(item))
+		}
+	}
+	return items
+}
+
+func (f *Flow) trusted(addr netip.Addr) bool {
+	for _, p := range f.trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteAddr parses an http.Request RemoteAddr ("ip:port" or a bare IP).
+func remoteAddr(s string) netip.Addr {
+	host, _, err := net.SplitHostPort(s)
+	if err != nil {
+		host = s
+	}
+	addr, _ := netip.ParseAddr(host)
+	return addr.Unmap()
+}
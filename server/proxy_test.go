@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseForwarded(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []forwardedElem
+	}{
+		{name: "empty"},
+		{name: "single", values: []string{"for=192.0.2.60;proto=https;host=example.com"},
+			want: []forwardedElem{{forNode: "192.0.2.60", proto: "https", host: "example.com"}}},
+		{name: "list", values: []string{"for=192.0.2.43, for=198.51.100.17"},
+			want: []forwardedElem{{forNode: "192.0.2.43"}, {forNode: "198.51.100.17"}}},
+		{name: "several headers", values: []string{"for=192.0.2.43", "for=198.51.100.17;proto=http"},
+			want: []forwardedElem{{forNode: "192.0.2.43"}, {forNode: "198.51.100.17", proto: "http"}}},
+		{name: "quoted ipv6", values: []string{`for="[2001:db8:cafe::17]:4711"`},
+			want: []forwardedElem{{forNode: "[2001:db8:cafe::17]:4711"}}},
+		{name: "case-insensitive names", values: []string{"For=192.0.2.60;PROTO=https;Host=example.com"},
+			want: []forwardedElem{{forNode: "192.0.2.60", proto: "https", host: "example.com"}}},
+		{name: "separators inside quotes", values: []string{`for="_a,b;c";host="example.com"`},
+			want: []forwardedElem{{forNode: "_a,b;c", host: "example.com"}}},
+		{name: "unknown and junk parameters", values: []string{"for=unknown;by=10.0.0.1;junk"},
+			want: []forwardedElem{{forNode: "unknown"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseForwarded(tt.values); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseForwarded = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		remote string
+		header map[string]string
+		ip     string
+		scheme string
+		host   string
+	}{
+		{name: "direct", remote: "203.0.113.5:1234", ip: "203.0.113.5", scheme: "http", host: "example.com"},
+		{name: "untrusted peer ignores headers", remote: "203.0.113.5:1234",
+			header: map[string]string{"X-Forwarded-For": "1.2.3.4", "Forwarded": "for=1.2.3.4;proto=https;host=evil.com", "X-Real-IP": "1.2.3.4"},
+			ip:     "203.0.113.5", scheme: "http", host: "example.com"},
+		{name: "x-forwarded-for", remote: "10.0.0.1:80",
+			header: map[string]string{"X-Forwarded-For": "198.51.100.7", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"},
+			ip:     "198.51.100.7", scheme: "https", host: "api.example.com"},
+		{name: "x-forwarded-for skips trusted hops", remote: "10.0.0.1:80",
+			header: map[string]string{"X-Forwarded-For": "6.6.6.6, 198.51.100.7, 10.0.0.2"},
+			ip:     "198.51.100.7", scheme: "http", host: "example.com"},
+		{name: "x-forwarded-proto aligned with client hop", remote: "10.0.0.1:80",
+			header: map[string]string{"X-Forwarded-For": "198.51.100.7, 10.0.0.2", "X-Forwarded-Proto": "https, http"},
+			ip:     "198.51.100.7", scheme: "https", host: "example.com"},
+		{name: "forwarded", remote: "10.0.0.1:80",
+			header: map[string]string{"Forwarded": `for=198.51.100.7;proto=https;host=api.example.com`},
+			ip:     "198.51.100.7", scheme: "https", host: "api.example.com"},
+		{name: "forwarded ipv6", remote: "10.0.0.1:80",
+			header: map[string]string{"Forwarded": `for="[2001:db8::1]:4711"`},
+			ip:     "2001:db8::1", scheme: "http", host: "example.com"},
+		{name: "forwarded skips trusted hops", remote: "10.0.0.1:80",
+			header: map[string]string{"Forwarded": "for=6.6.6.6, for=198.51.100.7;proto=https, for=10.0.0.2;proto=http"},
+			ip:     "198.51.100.7", scheme: "https", host: "example.com"},
+		{name: "forwarded wins over x-forwarded-for", remote: "10.0.0.1:80",
+			header: map[string]string{"Forwarded": "for=198.51.100.7", "X-Forwarded-For": "198.51.100.8"},
+			ip:     "198.51.100.7", scheme: "http", host: "example.com"},
+		{name: "x-real-ip fallback", remote: "10.0.0.1:80",
+			header: map[string]string{"X-Real-IP": "198.51.100.9"},
+			ip:     "198.51.100.9", scheme: "http", host: "example.com"},
+		{name: "all hops trusted", remote: "10.0.0.1:80",
+			header: map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"},
+			ip:     "10.0.0.1", scheme: "http", host: "example.com"},
+		{name: "mapped ipv4 peer", remote: "[::ffff:10.0.0.1]:80",
+			header: map[string]string{"X-Forwarded-For": "198.51.100.7"},
+			ip:     "198.51.100.7", scheme: "http", host: "example.com"},
+	}
+
+	f := NewFlow(WithTrustedProxies("10.0.0.0/8"))
+	f.Stream("GET", "/ip", nil, func(ctx *FlowContext) {
+		ctx.Response.Write([]byte(ctx.ClientIP() + " " + ctx.Scheme() + " " + ctx.Host()))
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com/ip", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			want := tt.ip + " " + tt.scheme + " " + tt.host
+			if got := f.Test(req).Body.String(); got != want {
+				t.Errorf("ClientIP Scheme Host = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package server
+
+import "strings"
+
+// QueryArray returns every value of the query parameter name, in order,
+// accepting both "tag=a&tag=b" and "tag[]=a&tag[]=b". Values are
+// percent-decoded; it returns nil when the parameter is absent.
+func (f *FlowContext) QueryArray(name string) []string {
+	query := f.Request.URL.Query()
+	values := query[name]
+	if bracketed := query[name+"[]"]; len(bracketed) > 0 {
+		values = append(values, bracketed...)
+	}
+	return values
+}
+
+// QueryMap collects bracketed query parameters under name into a map:
+// "filter[name]=x&filter[age]=y" gives {"name": "x", "age": "y"}. For a
+// repeated key the first value wins. It returns an empty map when none
+// are present.
+func (f *FlowContext) QueryMap(name string) map[string]string {
+	out := map[string]string{}
+	for key, values := range f.Request.URL.Query() {
+		inner, ok := strings.CutPrefix(key, name+"[")
+		if !ok || !strings.HasSuffix(inner, "]") || len(values) == 0 {
+			continue
+		}
+		inner = strings.TrimSuffix(inner, "]")
+		if inner == "" || strings.ContainsAny(inner, "[]") {
+			continue
+		}
+		out[inner] = values[0]
+	}
+	return out
+}
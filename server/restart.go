@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+)
+
+// listenerFDEnv tells a restarted process which inherited fd is its listener.
+const listenerFDEnv = "FLOWHTTP_LISTENER_FD"
+
+// WithGracefulRestart enables zero-downtime restarts for Run and RunTLS: on
+// SIGUSR2 the process re-executes its binary, handing the listening socket
+// to the new process, then drains and exits like a normal shutdown. The new
+// process picks the socket up instead of binding the port again, so no
+// connection is refused while the binary is replaced. Unix only; elsewhere
+// the option has no effect.
+func WithGracefulRestart() Option {
+	return func(f *Flow) { f.restart = true }
+}
+
+// listen binds addr, or adopts the listener handed over by a graceful restart.
+func (f *Flow) listen(addr string) (net.Listener, error) {
+	if f.restart {
+		if fd := os.Getenv(listenerFDEnv); fd != "" {
+			os.Unsetenv(listenerFDEnv)
+			n, err := strconv.Atoi(fd)
+			if err != nil {
+				return nil, fmt.Errorf("server error: invalid %s %q", listenerFDEnv, fd)
+			}
+			file := os.NewFile(uintptr(n), "listener")
+			defer file.Close()
+			l, err := net.FileListener(file)
+			if err != nil {
+				return nil, fmt.Errorf("server error: inherit listener: %v", err)
+			}
+			return l, nil
+		}
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("server error: %v", err)
+	}
+	return l, nil
+}
+
+// watchRestart returns a context canceled once the listener has been handed
+// to a new process on a restart signal. Failed restarts are logged and the
+// current process keeps serving.
+func (f *Flow) watchRestart(parent context.Context, l net.Listener) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	if len(restartSignals) == 0 {
+		return ctx, cancel
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, restartSignals...)
+	go func() {
+		defer signal.Stop(sig)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sig:
+				if err := forkWithListener(l); err != nil {
+					f.logger().Error("flowhttp: graceful restart failed", "error", err)
+					continue
+				}
+				f.logger().Info("flowhttp: listener handed to new process, draining")
+				cancel()
+				return
+			}
+		}
+	}()
+	return ctx, cancel
+}
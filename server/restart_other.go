@@ -0,0 +1,15 @@
+//go:build !unix
+
+package server
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+var restartSignals []os.Signal
+
+func forkWithListener(net.Listener) error {
+	return errors.New("graceful restart is not supported on this platform")
+}
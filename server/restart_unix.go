@@ -0,0 +1,44 @@
+//go:build unix
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+)
+
+var restartSignals = []os.Signal{syscall.SIGUSR2}
+
+// forkWithListener starts the current executable with l's socket as fd 3.
+func forkWithListener(l net.Listener) error {
+	fl, ok := l.(interface{ File() (*os.File, error) })
+	if !ok {
+		return fmt.Errorf("listener %T cannot be handed over", l)
+	}
+	file, err := fl.File()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	env := make([]string, 0, len(os.Environ())+1)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, listenerFDEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env, listenerFDEnv+"=3")
+
+	_, err = os.StartProcess(exe, os.Args, &os.ProcAttr{
+		Env:   env,
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr, file},
+	})
+	return err
+}
@@ -0,0 +1,87 @@
+package server
+
+import "net/http"
+
+const responseBufferKey = "response.buffer"
+
+// ResponseBuffer is a downstream response held back by BufferResponse.
+type ResponseBuffer struct {
+	w *bufferWriter
+}
+
+// Status returns the captured status code.
+func (b *ResponseBuffer) Status() int { return b.w.statusCode() }
+
+// Body returns the captured body.
+func (b *ResponseBuffer) Body() []byte { return b.w.buf.Bytes() }
+
+// Header returns the response header, still modifiable.
+func (b *ResponseBuffer) Header() http.Header { return b.w.Header() }
+
+// Streamed reports whether the response already went out (it outgrew the
+// buffer or the handler flushed), so it can no longer be rewritten.
+func (b *ResponseBuffer) Streamed() bool { return b.w.passthrough }
+
+// Rewrite replaces the captured status and body. It returns false if the
+// response was already streamed.
+func (b *ResponseBuffer) Rewrite(status int, body []byte) bool {
+	if b.w.passthrough {
+		return false
+	}
+	b.w.status = status
+	b.w.buf.Reset()
+	b.w.buf.Write(body)
+	b.w.Header().Del("Content-Length")
+	return true
+}
+
+// BufferResponse returns a Step that holds back the downstream response, up
+// to limit bytes (0 for no limit), until the chain returns. Steps after it
+// can inspect and rewrite it via ctx.ResponseBuffer() once next returns.
+// Larger or flushed responses are streamed as usual.
+func BufferResponse(limit int) Step {
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		bw := &bufferWriter{ResponseWriter: ctx.Response, limit: limit}
+		prev := ctx.Get(responseBufferKey)
+		orig := ctx.Response
+		ctx.Response = bw
+		ctx.Set(responseBufferKey, &ResponseBuffer{w: bw})
+		defer func() {
+			ctx.Response = orig
+			ctx.Set(responseBufferKey, prev)
+			if p := recover(); p != nil {
+				panic(p) // let recovery answer instead of a partial response
+			}
+			bw.flush()
+		}()
+		next(ctx)
+	})
+}
+
+// ResponseBuffer returns the innermost BufferResponse buffer, or nil.
+func (f *FlowContext) ResponseBuffer() *ResponseBuffer {
+	b, _ := f.Get(responseBufferKey).(*ResponseBuffer)
+	return b
+}
+
+// RewriteErrors returns a Step that buffers responses and calls rewrite for
+// 4xx/5xx ones before they are sent, e.g. to serve branded error pages or
+// convert error formats:
+//
+//	RewriteErrors(func(ctx *FlowContext, resp *ResponseBuffer) {
+//		if strings.Contains(ctx.Request.Header.Get("Accept"), "text/html") {
+//			resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+//			resp.Rewrite(resp.Status(), renderErrorPage(resp.Status()))
+//		}
+//	})
+func RewriteErrors(rewrite func(ctx *FlowContext, resp *ResponseBuffer)) Step {
+	buffer := BufferResponse(1 << 20)
+	return func(next Sink) Sink {
+		return buffer(func(ctx *FlowContext) {
+			next(ctx)
+			if b := ctx.ResponseBuffer(); !b.Streamed() && b.Status() >= 400 {
+				rewrite(ctx, b)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// PathPrefixKey is the ctx key under which StripPrefix stores the prefix
+// it removed.
+const PathPrefixKey = "path.prefix"
+
+// StripPrefix returns a Step removing prefix (e.g. "/myapp", added by an
+// ingress) from the request path, so routes can be declared without it.
+// Use it with WithPreRouting. Paths without the prefix pass unchanged.
+func StripPrefix(prefix string) Step {
+	prefix = "/" + strings.Trim(prefix, "/")
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		p := ctx.Request.URL.Path
+		if p != prefix && !strings.HasPrefix(p, prefix+"/") {
+			next(ctx)
+			return
+		}
+		rest := strings.TrimPrefix(ctx.Request.URL.EscapedPath(), prefix)
+		if rest == "" {
+			rest = "/"
+		}
+		ctx.Set(PathPrefixKey, prefix)
+		ctx.Request = withPath(ctx.Request, rest)
+		next(ctx)
+	})
+}
+
+// RewriteRule rewrites paths matching the regular expression From to To,
+// which may reference submatches ("$1", "${name}").
+type RewriteRule struct {
+	From string
+	To   string
+}
+
+// RewritePath returns a Step applying the first rule whose From matches
+// the request path (in its escaped form, so "%2F" stays distinct from "/"), e.g. RewriteRule{From: "^/api/v1/(.*)$", To: "/v1/$1"}.
+// Use it with WithPreRouting. It panics on an invalid expression.
+func RewritePath(rules ...RewriteRule) Step {
+	type compiled struct {
+		re *regexp.Regexp
+		to string
+	}
+	compiledRules := make([]compiled, len(rules))
+	for i, r := range rules {
+		re, err := regexp.Compile(r.From)
+		if err != nil {
+			panic(fmt.Errorf("rewrite rule %q: %v", r.From, err))
+		}
+		compiledRules[i] = compiled{re, r.To}
+	}
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		p := ctx.Request.URL.EscapedPath()
+		for _, r := range compiledRules {
+			if r.re.MatchString(p) {
+				ctx.Request = withPath(ctx.Request, r.re.ReplaceAllString(p, r.to))
+				break
+			}
+		}
+		next(ctx)
+	})
+}
+
+// withPath returns a shallow copy of req whose URL path is escaped.
+func withPath(req *http.Request, escaped string) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	u := *req.URL
+	if path, err := url.PathUnescape(escaped); err == nil {
+		u.Path, u.RawPath = path, escaped
+	} else {
+		u.Path, u.RawPath = escaped, ""
+	}
+	r.URL = &u
+	return r
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotateConfig configures a RotatingFile.
+type RotateConfig struct {
+	// Filename is the active log file; rotated files are kept next to it
+	// as name-<timestamp>.ext.
+	Filename string
+	// MaxSize rotates once the file would exceed this many bytes
+	// (defaults to 100 MiB).
+	MaxSize int64
+	// Interval additionally rotates on a schedule, e.g. 24h (off by default).
+	Interval time.Duration
+	// MaxBackups caps how many rotated files are kept (0 keeps all).
+	MaxBackups int
+	// Compress gzips rotated files in the background.
+	Compress bool
+}
+
+// RotatingFile is an io.Writer for logs (e.g. the AccessLog Step) that
+// rotates by size and/or time. It is safe for concurrent use.
+type RotatingFile struct {
+	cfg    RotateConfig
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	wg     sync.WaitGroup
+}
+
+// NewRotatingFile opens (or appends to) cfg.Filename.
+func NewRotatingFile(cfg RotateConfig) (*RotatingFile, error) {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 100 << 20
+	}
+	r := &RotatingFile{cfg: cfg}
+	if err := os.MkdirAll(filepath.Dir(cfg.Filename), 0o755); err != nil {
+		return nil, err
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.cfg.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file, r.size, r.opened = f, info.Size(), time.Now()
+	return nil
+}
+
+// Write appends p, rotating first if it is due.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	due := r.size > 0 && r.size+int64(len(p)) > r.cfg.MaxSize
+	if r.cfg.Interval > 0 && time.Since(r.opened) >= r.cfg.Interval {
+		due = true
+	}
+	if due {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate forces a rotation, e.g. from a SIGHUP handler.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	return r.rotate()
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	ext := filepath.Ext(r.cfg.Filename)
+	stamp := strings.TrimSuffix(r.cfg.Filename, ext) + "-" + time.Now().Format("20060102T150405.000")
+	backup := stamp + ext
+	for i := 1; exists(backup) || exists(backup+".gz"); i++ {
+		backup = fmt.Sprintf("%s.%d%s", stamp, i, ext)
+	}
+	if err := os.Rename(r.cfg.Filename, backup); err != nil {
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		if r.cfg.Compress {
+			compressFile(backup)
+		}
+		r.prune()
+	}()
+	return nil
+}
+
+// prune removes the oldest rotated files beyond MaxBackups.
+func (r *RotatingFile) prune() {
+	if r.cfg.MaxBackups <= 0 {
+		return
+	}
+	ext := filepath.Ext(r.cfg.Filename)
+	matches, _ := filepath.Glob(strings.TrimSuffix(r.cfg.Filename, ext) + "-*" + ext + "*")
+	sort.Strings(matches) // timestamps sort chronologically
+	for len(matches) > r.cfg.MaxBackups {
+		os.Remove(matches[0])
+		matches = matches[1:]
+	}
+}
+
+func exists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+func compressFile(name string) {
+	src, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+	dst, err := os.Create(name + ".gz")
+	if err != nil {
+		return
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name + ".gz")
+		return
+	}
+	os.Remove(name)
+}
+
+// Close closes the active file and waits for background compression.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+	return err
+}
@@ -2,14 +2,66 @@ package server
 
 import (
 	"fmt"
+	"net/url"
 	"regexp"
+	"slices"
 	"strings"
 )
 
+// Route describes a registered stream. It is available to Steps and Sinks
+// via ctx.Route() once the router has matched the request.
+type Route struct {
+	Method    string
+	Pattern   string
+	skip      []string
+	meta      map[string]any
+	providers map[string]Provider
+	maxBody   int64
+	consumes  []string
+	steps     []string
+}
+
+// SetMeta attaches metadata to the route, e.g. API docs or an owning team,
+// for Steps, tooling and generators such as the openapi package.
+func (r *Route) SetMeta(key string, value any) *Route {
+	if r.meta == nil {
+		r.meta = make(map[string]any)
+	}
+	r.meta[key] = value
+	return r
+}
+
+// Meta returns the metadata stored under key, or nil.
+func (r *Route) Meta(key string) any {
+	return r.meta[key]
+}
+
+// Skip disables the named (registered) steps for this route only.
+func (r *Route) Skip(names ...string) *Route {
+	r.skip = append(r.skip, names...)
+	return r
+}
+
+// Steps returns the names of the registered steps (see RegisterStep) the
+// route was declared with via f.Step or f.Steps, in order, including
+// inherited and skipped ones and ones wrapped by When or Unless.
+// Generic Steps can use it with Meta to act on route declarations instead
+// of matching paths.
+func (r *Route) Steps() []string {
+	return slices.Clone(r.steps)
+}
+
+// Skips reports whether the named step is disabled for this route.
+func (r *Route) Skips(name string) bool {
+	return slices.Contains(r.skip, name)
+}
+
 // internal types representing streams and methods
 type stream struct {
 	steps []Step
+	local []Step // steps declared below the APIVersion, if any
 	sink  Sink
+	route *Route
 }
 
 type streamMethods struct {
@@ -17,7 +69,20 @@ type streamMethods struct {
 	POST *stream
 }
 
+// get returns the stream for method, or nil. HEAD is served by the GET
+// stream; net/http drops the body.
+func (m *streamMethods) get(method string) *stream {
+	switch method {
+	case "GET", "HEAD":
+		return m.GET
+	case "POST":
+		return m.POST
+	}
+	return nil
+}
+
 type dynamicStream struct {
+	path          string
 	pattern       *regexp.Regexp
 	methods       *streamMethods
 	hasPathParams bool
@@ -25,7 +90,15 @@ type dynamicStream struct {
 
 // convertPathToRegex converts patterns like /user/:id or /files/*path to a named regex.
 // Returns compiled regex and whether the pattern contains named params.
+// Literal segments are percent-encoded, as the regex runs on the escaped path.
 func convertPathToRegex(path string) (*regexp.Regexp, bool) {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if !strings.ContainsAny(seg, ":*") {
+			segments[i] = url.PathEscape(seg)
+		}
+	}
+	path = strings.Join(segments, "/")
 	hasParams := false
 	re := regexp.MustCompile(`:([a-zA-Z0-9_]+)`)
 	if re.MatchString(path) {
@@ -36,22 +109,30 @@ func convertPathToRegex(path string) (*regexp.Regexp, bool) {
 	return regexp.MustCompile("^" + replaced + "$"), hasParams
 }
 
-// getStreamMethodsForPath resolves a path to either static or dynamic route.
+// getStreamMethodsForPath resolves a URL path to either static or dynamic route.
 // Returns streamMethods, extracted params (if any), or error when not found.
-func (f *Flow) getStreamMethodsForPath(path string) (*streamMethods, map[string]string, error) {
+// Dynamic routes are matched against the escaped path, so an encoded "/"
+// (%2F) stays inside its segment; param values are then percent-decoded.
+func (f *Flow) getStreamMethodsForPath(u *url.URL) (*streamMethods, map[string]string, error) {
+	path := u.Path
 	// static fast path
 	if methods, exists := f.streams[path]; exists {
 		return methods, nil, nil
 	}
 	// dynamic fallback (order preserved as registered)
+	raw := upperEscapes(u.EscapedPath())
 	for _, d := range f.dynamicStreams {
-		if d.pattern.MatchString(path) {
+		if d.pattern.MatchString(raw) {
 			params := make(map[string]string)
 			if d.hasPathParams {
-				matches := d.pattern.FindStringSubmatch(path)
+				matches := d.pattern.FindStringSubmatch(raw)
 				for i, name := range d.pattern.SubexpNames() {
 					if i != 0 && name != "" {
-						params[name] = matches[i]
+						value, err := url.PathUnescape(matches[i])
+						if err != nil {
+							value = matches[i]
+						}
+						params[name] = value
 					}
 				}
 			}
@@ -60,3 +141,63 @@ func (f *Flow) getStreamMethodsForPath(path string) (*streamMethods, map[string]
 	}
 	return nil, nil, fmt.Errorf("no route found for path: %s", path)
 }
+
+// upperEscapes uppercases the hex digits of percent-escapes, the form
+// route patterns are encoded in.
+func upperEscapes(p string) string {
+	if !strings.Contains(p, "%") {
+		return p
+	}
+	b := []byte(p)
+	for i := 0; i+2 < len(b); i++ {
+		if b[i] == '%' {
+			b[i+1], b[i+2] = upperHex(b[i+1]), upperHex(b[i+2])
+			i += 2
+		}
+	}
+	return string(b)
+}
+
+func upperHex(c byte) byte {
+	if 'a' <= c && c <= 'f' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+// Routes returns all registered routes in registration order.
+func (f *Flow) Routes() []*Route {
+	return slices.Clone(f.routes)
+}
+
+// stream returns the stream registered for method on exactly pattern, or nil.
+func (f *Flow) stream(method, pattern string) *stream {
+	m := f.streams[pattern]
+	if m == nil {
+		m = f.dynamicMethods(pattern)
+	}
+	if m == nil {
+		return nil
+	}
+	return m.get(method)
+}
+
+// Match reports which route a method+path request resolves to, along with
+// its path params, as the router would match it after WithCleanPaths. It
+// returns nil when the request would not be routed. path is the decoded URL
+// path. WithPreRouting steps don't run, so paths they would rewrite are
+// matched as given.
+func (f *Flow) Match(method, path string) (*Route, map[string]string) {
+	if f.cleanPaths {
+		path = cleanPath(path)
+	}
+	methods, params, err := f.getStreamMethodsForPath(&url.URL{Path: path})
+	if err != nil {
+		return nil, nil
+	}
+	s := methods.get(method)
+	if s == nil {
+		return nil, nil
+	}
+	return s.route, params
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scheduledJob is a job registered with Flow.Schedule.
+type scheduledJob struct {
+	spec  string
+	sched *cronSchedule
+	job   func(ctx context.Context)
+}
+
+// scheduler runs the scheduled jobs while the server is up.
+type scheduler struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Schedule runs job on a cron schedule while the server runs: it starts
+// with the server and stops on shutdown, when the job's context is
+// canceled and Shutdown waits (up to the shutdown timeout) for running
+// jobs. Runs of one job never overlap; a run that overruns its next slot
+// skips it.
+//
+// spec is a five-field cron expression in local time ("*/15 * * * *",
+// "0 3 * * mon-fri") supporting *, lists, ranges and steps, or one of
+// @hourly, @daily, @weekly, @monthly, @yearly and "@every <duration>".
+// It panics on an invalid spec.
+func (f *Flow) Schedule(spec string, job func(ctx context.Context)) {
+	sched, err := parseCron(spec)
+	if err != nil {
+		panic(fmt.Sprintf("invalid schedule %q: %v", spec, err))
+	}
+	f.scheduled = append(f.scheduled, &scheduledJob{spec: spec, sched: sched, job: job})
+}
+
+func (f *Flow) startSchedules() {
+	s := &f.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil || len(f.scheduled) == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	for _, sj := range f.scheduled {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			f.runSchedule(ctx, sj)
+		}()
+	}
+}
+
+func (f *Flow) runSchedule(ctx context.Context, sj *scheduledJob) {
+	for {
+		now := time.Now()
+		next := sj.sched.next(now)
+		if next.IsZero() {
+			return
+		}
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					f.logger().Error("scheduled job panicked", "schedule", sj.spec, "panic", p)
+				}
+			}()
+			sj.job(ctx)
+		}()
+	}
+}
+
+// stopSchedules cancels the scheduled jobs and waits for running ones
+// until ctx is done.
+func (f *Flow) stopSchedules(ctx context.Context) {
+	s := &f.scheduler
+	s.mu.Lock()
+	cancel := s.cancel
+	s.cancel = nil
+	s.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		f.logger().Warn("scheduled jobs still running at shutdown")
+	}
+}
+
+// cronSchedule is a parsed Schedule spec; each field is a bitset of
+// allowed values.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny/dowAny record a "*" day field: with both restricted, a day
+	// matching either one qualifies, as in classic cron.
+	domAny, dowAny bool
+	every          time.Duration
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+	dayNames   = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+func parseCron(spec string) (*cronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || every <= 0 {
+			return nil, fmt.Errorf("invalid duration %q", d)
+		}
+		return &cronSchedule{every: every}, nil
+	}
+	if m, ok := cronMacros[spec]; ok {
+		spec = m
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+	s := &cronSchedule{domAny: fields[2] == "*", dowAny: fields[4] == "*"}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, err
+	}
+	if s.dow&(1<<7) != 0 { // 7 is Sunday too
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+// parseCronField parses a comma-separated list of "*", "n", "a-b" with an
+// optional "/step".
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	value := func(s string) (int, error) {
+		if n, ok := names[strings.ToLower(s)]; ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("invalid value %q", s)
+		}
+		return n, nil
+	}
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+			step = n
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = value(a); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = value(b); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		}
+		for i := lo; i <= hi; i += step {
+			bits |= 1 << i
+		}
+	}
+	return bits, nil
+}
+
+// next returns the first activation after t, or the zero time if there is
+// none within five years (e.g. "0 0 30 2 *").
+func (s *cronSchedule) next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<t.Month()) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<t.Weekday()) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
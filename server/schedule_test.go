@@ -0,0 +1,83 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"x * * * *",
+		"* * * foo *",
+		"@every",
+		"@every -1m",
+		"@every soon",
+	}
+	for _, spec := range tests {
+		if _, err := parseCron(spec); err == nil {
+			t.Errorf("parseCron(%q): want an error", spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// Friday 2024-03-01 12:34:56 UTC
+	from := time.Date(2024, 3, 1, 12, 34, 56, 0, time.UTC)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		year := 2024
+		if month < time.March {
+			year = 2025
+		}
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", at(3, 1, 12, 35)},
+		{"*/15 * * * *", at(3, 1, 12, 45)},
+		{"30 * * * *", at(3, 1, 13, 30)},
+		{"0 9 * * *", at(3, 2, 9, 0)},
+		{"0 9-17/4 * * *", at(3, 1, 13, 0)},
+		{"0,40 12 * * *", at(3, 1, 12, 40)},
+		{"0 0 * * mon", at(3, 4, 0, 0)},
+		{"0 0 * * 7", at(3, 3, 0, 0)},
+		{"0 0 * * SUN", at(3, 3, 0, 0)},
+		{"0 0 1 * *", at(4, 1, 0, 0)},
+		{"0 0 1 jan *", at(1, 1, 0, 0)},
+		{"0 0 29 feb *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// both day fields restricted: either one matches
+		{"0 0 15 * mon", at(3, 4, 0, 0)},
+		// one day field "*": only the other one counts
+		{"0 0 15 * *", at(3, 15, 0, 0)},
+		{"0 0 * * fri", at(3, 8, 0, 0)},
+		{"@hourly", at(3, 1, 13, 0)},
+		{"@daily", at(3, 2, 0, 0)},
+		{"@weekly", at(3, 3, 0, 0)},
+		{"@monthly", at(4, 1, 0, 0)},
+		{"@yearly", at(1, 1, 0, 0)},
+		{"@every 90s", from.Add(90 * time.Second)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := parseCron(tt.spec)
+			if err != nil {
+				t.Fatalf("parseCron: %v", err)
+			}
+			if got := s.next(from); !got.Equal(tt.want) {
+				t.Errorf("next = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,10 +2,15 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 )
 
@@ -18,12 +23,39 @@ import (
 // You don’t need to call ServeHTTP directly — it’s used internally
 // so Flow can act as a standard HTTP handler.
 func (f *Flow) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	path := req.URL.Path
+	ctx := newFlowContext(w, req, nil)
+	ctx.flow = f
+	defer ctx.finish()
+	defer ctx.recoverPanic()
+	if len(f.preRouting) == 0 {
+		f.dispatch(ctx)
+		return
+	}
+	sink := Sink(f.dispatch)
+	for i := len(f.preRouting) - 1; i >= 0; i-- {
+		sink = f.preRouting[i](sink)
+	}
+	sink(ctx)
+}
+
+// dispatch routes ctx.Request and runs the matched stream with ctx, so
+// values and deferred funcs of WithPreRouting steps carry over and those
+// steps see the route once next returns.
+func (f *Flow) dispatch(ctx *FlowContext) {
+	w, req := ctx.Response, ctx.Request
 	method := req.Method
 
-	streamMethods, params, err := f.getStreamMethodsForPath(path)
+	if f.cleanPaths {
+		var ok bool
+		if req, ok = f.cleanRequest(w, req); !ok {
+			return
+		}
+	}
+
+	streamMethods, params, err := f.getStreamMethodsForPath(req.URL)
 	if err != nil {
-		http.NotFound(w, req)
+		ctx.Request = req
+		f.serveNotFound(ctx)
 		return
 	}
 
@@ -31,19 +63,15 @@ func (f *Flow) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	if params != nil {
 		req = req.WithContext(context.WithValue(req.Context(), paramsKey, params))
 	}
+	ctx.Request = req
 
-	var s *stream
-	switch method {
-	case http.MethodGet:
-		s = streamMethods.GET
-	case http.MethodPost:
-		s = streamMethods.POST
-	default:
+	if method != http.MethodGet && method != http.MethodHead && method != http.MethodPost {
 		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	s := streamMethods.get(method)
 	if s == nil {
-		http.NotFound(w, req)
+		f.serveNotFound(ctx)
 		return
 	}
 
@@ -53,51 +81,355 @@ func (f *Flow) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		sink = s.steps[i](sink)
 	}
 
-	// call the top-level sink (it will build FlowContext)
-	sink.ServeHTTP(w, req)
+	ctx.Params = params
+	ctx.route = s.route
+	defer ctx.recoverPanic()
+	if err := ctx.limitBody(); err != nil {
+		ctx.Error(err)
+		return
+	}
+	if err := ctx.checkContentType(); err != nil {
+		ctx.Error(err)
+		return
+	}
+	if f.bodyBuffer > 0 {
+		if err := ctx.bufferBody(f.bodyBuffer); err != nil {
+			ctx.Error(err)
+			return
+		}
+	}
+	sink(ctx)
+}
+
+// Test runs req through the full routing and Step pipeline without a
+// listener and returns the recorded response, for integration tests.
+func (f *Flow) Test(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	f.ServeHTTP(rec, req)
+	return rec
+}
+
+// serveNotFound answers unmatched requests with the WithNotFound sink, if any.
+func (f *Flow) serveNotFound(ctx *FlowContext) {
+	if f.notFound == nil {
+		http.NotFound(ctx.Response, ctx.Request)
+		return
+	}
+	f.notFound(ctx)
 }
 
 // Run starts the HTTP server and supports graceful shutdown.
-// port can be int, string (":8080" or "8080"), or nil (defaults to :8080).
+// port can be int, string (":8080", "8080" or "host:8080"), or nil (defaults to :8080).
+// Bind errors (port in use, permission denied) are returned immediately;
+// with port 0, OnStart hooks and Addr report the port actually bound.
 func (f *Flow) Run(port any) error {
-	addr := ":8080"
-	switch v := port.(type) {
-	case nil:
-	case int:
-		addr = fmt.Sprintf(":%d", v)
-	case string:
-		if v != "" {
-			if v[0] != ':' {
-				addr = ":" + v
-			} else {
-				addr = v
-			}
+	addr, err := resolveAddr(port)
+	if err != nil {
+		return err
+	}
+	l, err := f.listen(addr)
+	if err != nil {
+		return err
+	}
+	return f.serve(l, true, func(srv *http.Server, l net.Listener) error {
+		return srv.Serve(l)
+	})
+}
+
+// RunTLS is like Run but serves HTTPS using the given certificate and key
+// files. Both may be empty when WithTLSConfig supplies Certificates or
+// GetCertificate.
+func (f *Flow) RunTLS(port any, certFile, keyFile string) error {
+	addr, err := resolveAddr(port)
+	if err != nil {
+		return err
+	}
+	l, err := f.listen(addr)
+	if err != nil {
+		return err
+	}
+	return f.serve(l, true, func(srv *http.Server, l net.Listener) error {
+		return srv.ServeTLS(l, certFile, keyFile)
+	})
+}
+
+// ServerConfig tunes the underlying http.Server. Zero fields keep the
+// defaults noted below.
+type ServerConfig struct {
+	// ReadHeaderTimeout bounds reading request headers (defaults to 10s),
+	// the main defence against slowloris-style clients.
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout bounds reading the whole request, body included (no limit by default).
+	ReadTimeout time.Duration
+	// WriteTimeout bounds writing the response (no limit by default, so
+	// streaming/SSE handlers aren't cut off).
+	WriteTimeout time.Duration
+	// IdleTimeout bounds keep-alive idle time (defaults to 120s).
+	IdleTimeout time.Duration
+	// MaxHeaderBytes caps request header size (defaults to 1 MiB).
+	MaxHeaderBytes int
+	// DisableKeepAlives turns off HTTP keep-alives.
+	DisableKeepAlives bool
+}
+
+func (c ServerConfig) apply(srv *http.Server) {
+	srv.ReadHeaderTimeout = 10 * time.Second
+	if c.ReadHeaderTimeout > 0 {
+		srv.ReadHeaderTimeout = c.ReadHeaderTimeout
+	}
+	srv.IdleTimeout = 120 * time.Second
+	if c.IdleTimeout > 0 {
+		srv.IdleTimeout = c.IdleTimeout
+	}
+	srv.ReadTimeout = c.ReadTimeout
+	srv.WriteTimeout = c.WriteTimeout
+	srv.MaxHeaderBytes = c.MaxHeaderBytes
+	if c.DisableKeepAlives {
+		srv.SetKeepAlivesEnabled(false)
+	}
+}
+
+// RunContext is like Run but doesn't install signal handlers: the server
+// shuts down gracefully when ctx is canceled, which suits embedding FlowHTTP
+// in larger programs and tests.
+func (f *Flow) RunContext(ctx context.Context, port any) error {
+	addr, err := resolveAddr(port)
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("server error: %v", err)
+	}
+	return f.serveContext(ctx, l, func(srv *http.Server, l net.Listener) error {
+		return srv.Serve(l)
+	})
+}
+
+// RunTLSContext is like RunTLS but, like RunContext, shuts down when ctx is
+// canceled instead of on a signal.
+func (f *Flow) RunTLSContext(ctx context.Context, port any, certFile, keyFile string) error {
+	addr, err := resolveAddr(port)
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("server error: %v", err)
+	}
+	return f.serveContext(ctx, l, func(srv *http.Server, l net.Listener) error {
+		return srv.ServeTLS(l, certFile, keyFile)
+	})
+}
+
+// RunUnix is like Run but listens on a unix domain socket at path with the
+// given permissions, e.g. behind a local nginx or envoy. A stale socket
+// file left by a previous run is replaced, but RunUnix fails if another
+// process is still accepting on it. The socket is removed on shutdown.
+func (f *Flow) RunUnix(path string, perms os.FileMode) error {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		c, err := net.DialTimeout("unix", path, time.Second)
+		if err == nil {
+			c.Close()
+			return fmt.Errorf("server error: %s is in use by another process", path)
 		}
-	default:
-		return fmt.Errorf("invalid port type")
+		if !errors.Is(err, syscall.ECONNREFUSED) {
+			return fmt.Errorf("server error: probe %s: %v", path, err)
+		}
+		os.Remove(path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("server error: %v", err)
+	}
+	defer os.Remove(path)
+	if err := os.Chmod(path, perms); err != nil {
+		l.Close()
+		return fmt.Errorf("server error: %v", err)
 	}
+	return f.serve(l, false, func(srv *http.Server, l net.Listener) error {
+		return srv.Serve(l)
+	})
+}
 
-	srv := &http.Server{Addr: addr, Handler: f}
-	errChan := make(chan error, 1)
+// Serve is like Run but serves on an existing listener, e.g. one bound to
+// port 0 in tests, a custom (proxy-protocol, throttled) listener, or one
+// handed over by a supervisor. The listener is closed when Serve returns.
+func (f *Flow) Serve(l net.Listener) error {
+	return f.serve(l, false, func(srv *http.Server, l net.Listener) error {
+		return srv.Serve(l)
+	})
+}
 
-	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			errChan <- err
+// ServeContext is like Serve but stops when ctx is canceled instead of on
+// a shutdown signal.
+func (f *Flow) ServeContext(ctx context.Context, l net.Listener) error {
+	return f.serveContext(ctx, l, func(srv *http.Server, l net.Listener) error {
+		return srv.Serve(l)
+	})
+}
+
+// Addr returns the address the running server is bound to, or nil when it
+// isn't running.
+func (f *Flow) Addr() net.Addr {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.addr
+}
+
+// Shutdown gracefully stops the running server: it signals long-lived
+// handlers via ctx.Draining(), waits for active requests until ctx expires,
+// waits up to the drain timeout for LongLived connections and up to the
+// shutdown timeout for scheduled and enqueued background jobs, then runs
+// OnShutdown hooks. It is a no-op if the server is not running. Only the
+// first call runs that sequence; concurrent and later calls wait for it
+// (or for their own ctx) and return its error.
+func (f *Flow) Shutdown(ctx context.Context) (err error) {
+	f.mu.Lock()
+	srv, stopped := f.srv, f.stopped
+	first := false
+	if f.draining != nil {
+		select {
+		case <-f.draining:
+		default:
+			close(f.draining)
+			first = true
+		}
+	}
+	f.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+	if !first {
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.shutdownErr
+	}
+	defer func() {
+		f.mu.Lock()
+		f.shutdownErr = err
+		f.mu.Unlock()
+		// lets serveContext, which returns as soon as the listener closes,
+		// and any concurrent Shutdown callers wait for the rest of the
+		// shutdown
+		close(stopped)
 	}()
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
+	err = srv.Shutdown(ctx)
+	f.waitLongLived()
+	jctx, cancel := context.WithTimeout(context.Background(), f.shutdownWait())
+	f.stopSchedules(jctx)
+	f.drainJobs(jctx)
+	cancel()
+	f.runShutdownHooks()
+	if err != nil {
+		return fmt.Errorf("shutdown error: %v", err)
+	}
+	return nil
+}
+
+// serve runs start until it fails or one of the configured signals
+// triggers a graceful shutdown. handover marks listeners bound by listen,
+// which a graceful restart may pass on to a new process.
+func (f *Flow) serve(l net.Listener, handover bool, start func(*http.Server, net.Listener) error) error {
+	ctx := context.Background()
+	if len(f.signals) > 0 {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, f.signals...)
+		defer stop()
+	}
+	if f.restart && handover {
+		var stop context.CancelFunc
+		ctx, stop = f.watchRestart(ctx, l)
+		defer stop()
+	}
+	return f.serveContext(ctx, l, start)
+}
+
+// serveContext runs start on the bound listener in the background and
+// blocks until it fails, Shutdown is called, or ctx is canceled (which
+// triggers Shutdown). OnStart hooks run once before serving begins and
+// OnStop hooks once serving has ended.
+func (f *Flow) serveContext(ctx context.Context, l net.Listener, start func(*http.Server, net.Listener) error) error {
+	srv := &http.Server{Addr: l.Addr().String(), Handler: f, TLSConfig: f.tlsConfig}
+	f.serverConfig.apply(srv)
+	f.mu.Lock()
+	f.srv = srv
+	f.addr = l.Addr()
+	f.draining = make(chan struct{})
+	f.stopped = make(chan struct{})
+	f.shutdownErr = nil
+	stopped := f.stopped
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		if f.srv == srv {
+			f.srv = nil
+			f.addr = nil
+		}
+		f.mu.Unlock()
+		sctx, cancel := context.WithTimeout(context.Background(), f.shutdownWait())
+		f.stopSchedules(sctx)
+		cancel()
+		f.runStopHooks()
+	}()
+
+	for _, fn := range f.onStart {
+		fn(srv.Addr)
+	}
+	f.startSchedules()
+
+	errChan := make(chan error, 1)
+	go func() { errChan <- start(srv, l) }()
 
 	select {
-	case <-quit:
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	case <-ctx.Done():
+		// a serve failure that raced with the cancellation still wins
+		select {
+		case err := <-errChan:
+			return serveError(err)
+		default:
+		}
+		sctx, cancel := context.WithTimeout(context.Background(), f.shutdownWait())
 		defer cancel()
-		if err := srv.Shutdown(ctx); err != nil {
-			return fmt.Errorf("shutdown error: %v", err)
+		return f.Shutdown(sctx)
+	case err := <-errChan:
+		if errors.Is(err, http.ErrServerClosed) {
+			// an external Shutdown is still draining; OnStop hooks must
+			// wait for it
+			<-stopped
 		}
+		return serveError(err)
+	}
+}
+
+func serveError(err error) error {
+	if err == nil || errors.Is(err, http.ErrServerClosed) {
 		return nil
-	case err := <-errChan:
-		return fmt.Errorf("server error: %v", err)
 	}
+	return fmt.Errorf("server error: %v", err)
+}
+
+// resolveAddr turns Run's port argument into a listen address.
+func resolveAddr(port any) (string, error) {
+	switch v := port.(type) {
+	case nil:
+	case int:
+		return fmt.Sprintf(":%d", v), nil
+	case string:
+		if v != "" {
+			if !strings.Contains(v, ":") {
+				return ":" + v, nil
+			}
+			return v, nil
+		}
+	default:
+		return "", fmt.Errorf("invalid port type")
+	}
+	return ":8080", nil
 }
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// SessionKey is the ctx key under which the Sessions Step stores the *Session.
+const SessionKey = "session"
+
+// Session holds per-client values persisted in a Store between requests.
+// Values round-trip through JSON, so numbers come back as float64.
+type Session struct {
+	id        string
+	values    map[string]any
+	changed   bool
+	destroyed bool
+	oldID     string
+}
+
+// ID returns the session identifier (also the cookie value).
+func (s *Session) ID() string { return s.id }
+
+// Get returns a session value (nil if missing).
+func (s *Session) Get(key string) any { return s.values[key] }
+
+// Set stores a session value.
+func (s *Session) Set(key string, value any) {
+	s.values[key] = value
+	s.changed = true
+}
+
+// Delete removes a session value.
+func (s *Session) Delete(key string) {
+	delete(s.values, key)
+	s.changed = true
+}
+
+// Destroy clears the session and expires the cookie.
+func (s *Session) Destroy() {
+	s.values = make(map[string]any)
+	s.destroyed = true
+}
+
+// Regenerate issues a new ID for the same values. Call it after login to
+// prevent session fixation.
+func (s *Session) Regenerate() {
+	if s.oldID == "" {
+		s.oldID = s.id
+	}
+	s.id = newSessionID()
+	s.changed = true
+}
+
+// SessionConfig configures the Sessions Step.
+type SessionConfig struct {
+	// Store persists session data (defaults to a new MemoryStore).
+	Store Store
+	// CookieName defaults to "flowhttp_session".
+	CookieName string
+	// TTL is both the store expiry and the cookie Max-Age (defaults to 24h).
+	TTL time.Duration
+	// KeyPrefix namespaces session keys in a shared Store (defaults to "session:").
+	KeyPrefix string
+	Path      string
+	Domain    string
+	Secure    bool
+	SameSite  http.SameSite
+}
+
+// Sessions returns a Step that loads the session named by the request cookie,
+// exposes it via ctx.Session(), and saves it before the response is written.
+func Sessions(cfg SessionConfig) Step {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = "flowhttp_session"
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "session:"
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		sess := cfg.load(ctx)
+		ctx.Set(SessionKey, sess)
+
+		w := &hookWriter{ResponseWriter: ctx.Response}
+		w.before = func() { cfg.save(ctx, w.ResponseWriter, sess) }
+		ctx.Response = w
+		next(ctx)
+		w.fire()
+		ctx.Response = w.ResponseWriter
+	})
+}
+
+// Session returns the session loaded by the Sessions Step (nil if none).
+func (f *FlowContext) Session() *Session {
+	s, _ := f.Get(SessionKey).(*Session)
+	return s
+}
+
+func (cfg *SessionConfig) load(ctx *FlowContext) *Session {
+	if c, err := ctx.Request.Cookie(cfg.CookieName); err == nil && c.Value != "" {
+		data, err := cfg.Store.Get(ctx.Request.Context(), cfg.KeyPrefix+c.Value)
+		if err == nil && data != nil {
+			values := make(map[string]any)
+			if json.Unmarshal(data, &values) == nil {
+				return &Session{id: c.Value, values: values}
+			}
+		}
+	}
+	return &Session{id: newSessionID(), values: make(map[string]any)}
+}
+
+// save persists a changed session and writes (or expires) the cookie.
+// Store errors are logged but not fatal: the request proceeds without a
+// persisted session, and a regenerated session keeps its old ID stored.
+func (cfg *SessionConfig) save(ctx *FlowContext, w http.ResponseWriter, s *Session) {
+	rctx := ctx.Request.Context()
+	log := ctx.flow.logger()
+	cookie := &http.Cookie{
+		Name:     cfg.CookieName,
+		Value:    s.id,
+		Path:     cfg.Path,
+		Domain:   cfg.Domain,
+		Secure:   cfg.Secure,
+		HttpOnly: true,
+		SameSite: cfg.SameSite,
+	}
+	if s.destroyed {
+		for _, id := range []string{s.oldID, s.id} {
+			if id == "" {
+				continue
+			}
+			if err := cfg.Store.Delete(rctx, cfg.KeyPrefix+id); err != nil {
+				log.Error("flowhttp: delete session", "error", err)
+			}
+		}
+		cookie.Value = ""
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+		return
+	}
+	if !s.changed {
+		return
+	}
+	data, err := json.Marshal(s.values)
+	if err != nil {
+		log.Error("flowhttp: encode session", "error", err)
+		return
+	}
+	if err := cfg.Store.Set(rctx, cfg.KeyPrefix+s.id, data, cfg.TTL); err != nil {
+		log.Error("flowhttp: save session", "error", err)
+		return
+	}
+	if s.oldID != "" {
+		if err := cfg.Store.Delete(rctx, cfg.KeyPrefix+s.oldID); err != nil {
+			log.Error("flowhttp: delete regenerated session", "error", err)
+		}
+	}
+	cookie.MaxAge = int(cfg.TTL / time.Second)
+	http.SetCookie(w, cookie)
+}
+
+func newSessionID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
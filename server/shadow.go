@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/datanadhi/flowhttp/client"
+)
+
+// ShadowConfig configures the Shadow Step.
+type ShadowConfig struct {
+	// Upstream is the base URL mirrored requests are sent to, e.g.
+	// "http://orders-v2.internal:8080"; the request URI is appended.
+	Upstream string
+	// Percent of requests to mirror, 0-100.
+	Percent float64
+	// Client sends the copies (defaults to a client with a 10s timeout).
+	Client *client.Client
+	// MaxInFlight bounds concurrent mirrored requests; beyond it requests
+	// aren't mirrored (defaults to 100).
+	MaxInFlight int
+}
+
+// hopHeaders are connection-specific and not forwarded.
+var hopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+// Shadow returns a Step that asynchronously mirrors a share of requests,
+// body included, to a secondary upstream, e.g. to try a new service
+// version against production traffic. Copies carry "X-Shadow: true";
+// their responses are discarded and never affect the real one.
+func Shadow(cfg ShadowConfig) Step {
+	if cfg.Client == nil {
+		cfg.Client = client.NewClient(10 * time.Second)
+	}
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = 100
+	}
+	upstream := strings.TrimSuffix(cfg.Upstream, "/")
+	slots := make(chan struct{}, cfg.MaxInFlight)
+
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		if cfg.Percent <= 0 || rand.Float64()*100 >= cfg.Percent {
+			next(ctx)
+			return
+		}
+		select {
+		case slots <- struct{}{}:
+		default:
+			next(ctx)
+			return
+		}
+		body, err := ctx.RawBody()
+		if err != nil {
+			<-slots
+			next(ctx)
+			return
+		}
+		req, err := http.NewRequestWithContext(context.WithoutCancel(ctx.Request.Context()),
+			ctx.Request.Method, upstream+ctx.Request.URL.RequestURI(), bytes.NewReader(body))
+		if err != nil {
+			<-slots
+			next(ctx)
+			return
+		}
+		req.Header = ctx.Request.Header.Clone()
+		for _, h := range hopHeaders {
+			req.Header.Del(h)
+		}
+		req.Header.Set("X-Shadow", "true")
+		log := ctx.flow.logger()
+
+		go func() {
+			defer func() { <-slots }()
+			resp, err := cfg.Client.Do(req)
+			if err != nil {
+				log.Debug("shadow request failed", "url", req.URL.String(), "error", err)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+		next(ctx)
+	})
+}
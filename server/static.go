@@ -0,0 +1,346 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// immutableCache is sent for fingerprinted assets, whose URL changes with
+// their content.
+const immutableCache = "public, max-age=31536000, immutable"
+
+// StaticConfig configures Branch.Static.
+type StaticConfig struct {
+	// Index is served for directory requests (defaults to "index.html").
+	Index string
+	// CacheControl is sent for files that aren't fingerprinted (defaults
+	// to "no-cache", i.e. revalidate via Last-Modified).
+	CacheControl string
+	// Assets serves the fingerprinted names of its manifest with
+	// far-future caching.
+	Assets *Assets
+	// Precompressed serves a sibling "<file>.br" or "<file>.gz" with the
+	// matching Content-Encoding when the client accepts it. See Precompress
+	// for generating .gz siblings at startup.
+	Precompressed bool
+	// Browse lists directories without an Index file, sortable with
+	// ?sort=name|size|time and &order=desc.
+	Browse bool
+	// ShowHidden includes dot-files in listings (they are still served).
+	ShowHidden bool
+	// ListTemplate replaces the built-in listing page; it is executed
+	// with a DirListing.
+	ListTemplate *template.Template
+}
+
+// Static serves the files of fsys (os.DirFS, embed.FS, ...) under prefix,
+// e.g. b.Static("/static", os.DirFS("public"), StaticConfig{}).
+func (b *Branch) Static(prefix string, fsys fs.FS, cfg StaticConfig) *Route {
+	if cfg.Index == "" {
+		cfg.Index = "index.html"
+	}
+	if cfg.CacheControl == "" {
+		cfg.CacheControl = "no-cache"
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	mount := b.path + prefix
+	return b.Stream("GET", prefix+"/*", nil, func(ctx *FlowContext) {
+		name := path.Clean("/" + strings.TrimPrefix(ctx.Request.URL.Path, mount))[1:]
+		if name == "" {
+			name = "."
+		}
+		cacheControl := cfg.CacheControl
+		if cfg.Assets != nil {
+			if orig, ok := cfg.Assets.reverse[name]; ok {
+				name, cacheControl = orig, immutableCache
+			}
+		}
+		serveFS(ctx, fsys, name, cfg, cacheControl)
+	})
+}
+
+// serveFS serves name from fsys through http.ServeContent, so conditional
+// and Range requests work.
+func serveFS(ctx *FlowContext, fsys fs.FS, name string, cfg StaticConfig, cacheControl string) {
+	if !fs.ValidPath(name) {
+		ctx.fail(http.StatusNotFound, "")
+		return
+	}
+	file, err := fsys.Open(name)
+	if err != nil {
+		ctx.fail(http.StatusNotFound, "")
+		return
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if stat.IsDir() {
+		file.Close()
+		dir := name
+		name = path.Join(name, cfg.Index)
+		if file, err = fsys.Open(name); err != nil {
+			if cfg.Browse {
+				listDir(ctx, fsys, dir, cfg)
+				return
+			}
+			ctx.fail(http.StatusNotFound, "")
+			return
+		}
+		defer file.Close()
+		if stat, err = file.Stat(); err != nil || stat.IsDir() {
+			ctx.fail(http.StatusNotFound, "")
+			return
+		}
+	}
+
+	h := ctx.Response.Header()
+	if cfg.Precompressed {
+		h.Add("Vary", "Accept-Encoding")
+		if enc, encoded := openEncoded(fsys, name, ctx.Request.Header.Get("Accept-Encoding")); encoded != nil {
+			defer encoded.Close()
+			if encStat, err := encoded.Stat(); err == nil {
+				h.Set("Content-Encoding", enc)
+				if h.Get("Content-Type") == "" {
+					if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+						h.Set("Content-Type", ctype)
+					}
+				}
+				file, stat = encoded, encStat
+			}
+		}
+	}
+
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	h.Set("Cache-Control", cacheControl)
+	http.ServeContent(ctx.Response, ctx.Request, path.Base(name), stat.ModTime(), content)
+}
+
+// File sends the named file from disk via http.ServeContent, which handles
+// Content-Type, Last-Modified/If-Modified-Since, Range and If-Range, so
+// large downloads can be resumed.
+func (f *FlowContext) File(name string) {
+	file, err := os.Open(name)
+	if err != nil {
+		f.fail(http.StatusNotFound, "")
+		return
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		f.fail(http.StatusNotFound, "")
+		return
+	}
+	http.ServeContent(f.Response, f.Request, stat.Name(), stat.ModTime(), file)
+}
+
+// Attachment is like File but asks the browser to save the file as filename.
+func (f *FlowContext) Attachment(name, filename string) {
+	f.Response.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	f.File(name)
+}
+
+// precompressedExts maps encodings to sibling file extensions, in order of
+// preference.
+var precompressedExts = []struct{ encoding, ext string }{{"br", ".br"}, {"gzip", ".gz"}}
+
+// openEncoded opens the preferred precompressed sibling of name that the
+// Accept-Encoding header allows.
+func openEncoded(fsys fs.FS, name, acceptEncoding string) (string, fs.File) {
+	for _, e := range precompressedExts {
+		if !acceptsEncoding(acceptEncoding, e.encoding) {
+			continue
+		}
+		if f, err := fsys.Open(name + e.ext); err == nil {
+			if st, err := f.Stat(); err == nil && !st.IsDir() {
+				return e.encoding, f
+			}
+			f.Close()
+		}
+	}
+	return "", nil
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows enc.
+func acceptsEncoding(header, enc string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.TrimSpace(name)
+		if !strings.EqualFold(name, enc) && name != "*" {
+			continue
+		}
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if q, err := strconv.ParseFloat(v, 64); err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Precompress returns fsys overlaid with in-memory gzip siblings ("app.js.gz")
+// of compressible files of at least minSize bytes, for serving an embed.FS
+// with StaticConfig.Precompressed without compressing per request.
+func Precompress(fsys fs.FS, minSize int64) (fs.FS, error) {
+	overlay := &gzipFS{FS: fsys, files: make(map[string]*memFile)}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !compressible(name) {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() < minSize {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		zw.Write(data)
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		if buf.Len() < len(data) {
+			overlay.files[name+".gz"] = &memFile{data: buf.Bytes(), name: path.Base(name) + ".gz", modTime: info.ModTime()}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return overlay, nil
+}
+
+// compressible skips formats that are already compressed.
+func compressible(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".gz", ".br", ".zip", ".png", ".jpg", ".jpeg", ".gif", ".webp", ".avif", ".woff", ".woff2", ".mp4", ".webm", ".mp3":
+		return false
+	}
+	return true
+}
+
+// gzipFS overlays generated .gz files on an fs.FS.
+type gzipFS struct {
+	fs.FS
+	files map[string]*memFile
+}
+
+func (g *gzipFS) Open(name string) (fs.File, error) {
+	if f, ok := g.files[name]; ok {
+		return &openMemFile{memFile: f, Reader: bytes.NewReader(f.data)}, nil
+	}
+	return g.FS.Open(name)
+}
+
+// memFile is an in-memory file; it doubles as its own fs.FileInfo.
+type memFile struct {
+	data    []byte
+	name    string
+	modTime time.Time
+}
+
+func (f *memFile) Name() string       { return f.name }
+func (f *memFile) Size() int64        { return int64(len(f.data)) }
+func (f *memFile) Mode() fs.FileMode  { return 0o444 }
+func (f *memFile) ModTime() time.Time { return f.modTime }
+func (f *memFile) IsDir() bool        { return false }
+func (f *memFile) Sys() any           { return nil }
+
+type openMemFile struct {
+	*memFile
+	*bytes.Reader
+}
+
+func (f *openMemFile) Stat() (fs.FileInfo, error) { return f.memFile, nil }
+func (f *openMemFile) Close() error               { return nil }
+
+// Assets is a manifest of content-hashed file names for cache busting:
+// "js/app.js" is published as "js/app.3f2a9c1b.js", so it can be cached
+// forever and still changes URL on every deploy that changes it.
+type Assets struct {
+	prefix   string
+	manifest map[string]string // original -> fingerprinted
+	reverse  map[string]string // fingerprinted -> original
+}
+
+// NewAssets fingerprints every file in fsys, to be served by a Static
+// route mounted at prefix with StaticConfig.Assets set.
+func NewAssets(fsys fs.FS, prefix string) (*Assets, error) {
+	a := &Assets{
+		prefix:   strings.TrimSuffix(prefix, "/"),
+		manifest: make(map[string]string),
+		reverse:  make(map[string]string),
+	}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		file, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, file); err != nil {
+			return err
+		}
+		ext := path.Ext(name)
+		hashed := strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(h.Sum(nil))[:8] + ext
+		a.manifest[name] = hashed
+		a.reverse[hashed] = name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Path returns the URL of the named asset, fingerprinted when it is in the
+// manifest.
+func (a *Assets) Path(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if hashed, ok := a.manifest[name]; ok {
+		name = hashed
+	}
+	return a.prefix + "/" + name
+}
+
+// Manifest returns a copy of the original -> fingerprinted name mapping.
+func (a *Assets) Manifest() map[string]string {
+	m := make(map[string]string, len(a.manifest))
+	for k, v := range a.manifest {
+		m[k] = v
+	}
+	return m
+}
+
+// FuncMap provides the asset template func: {{asset "app.js"}}.
+func (a *Assets) FuncMap() template.FuncMap {
+	return template.FuncMap{"asset": a.Path}
+}
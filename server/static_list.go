@@ -0,0 +1,124 @@
+package server
+
+import (
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DirListing is the data passed to StaticConfig.ListTemplate.
+type DirListing struct {
+	// Path is the directory relative to the static root, e.g. "/js/".
+	Path    string
+	Entries []DirEntry
+	Sort    string
+	Order   string
+}
+
+// DirEntry is one file or directory in a DirListing.
+type DirEntry struct {
+	Name    string
+	URL     string
+	IsDir   bool
+	Size    int64
+	ModTime time.Time
+}
+
+var dirListPage = template.Must(template.New("dir").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Index of {{.Path}}</title>
+<style>body{font-family:sans-serif;margin:2em}td,th{padding:.2em 1em;text-align:left}</style></head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<table>
+<tr><th><a href="?sort=name{{if and (eq .Sort "name") (ne .Order "desc")}}&amp;order=desc{{end}}">Name</a></th>
+<th><a href="?sort=size{{if and (eq .Sort "size") (ne .Order "desc")}}&amp;order=desc{{end}}">Size</a></th>
+<th><a href="?sort=time{{if and (eq .Sort "time") (ne .Order "desc")}}&amp;order=desc{{end}}">Modified</a></th></tr>
+{{if ne .Path "/"}}<tr><td><a href="../">../</a></td><td></td><td></td></tr>{{end}}
+{{range .Entries}}<tr><td><a href="{{.URL}}">{{.Name}}{{if .IsDir}}/{{end}}</a></td><td>{{if not .IsDir}}{{.Size}}{{end}}</td><td>{{.ModTime.Format "2006-01-02 15:04"}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// listDir renders the directory index for dir.
+func listDir(ctx *FlowContext, fsys fs.FS, dir string, cfg StaticConfig) {
+	// relative entry links need the trailing slash
+	if !strings.HasSuffix(ctx.Request.URL.Path, "/") {
+		target := ctx.Request.URL.Path + "/"
+		if q := ctx.Request.URL.RawQuery; q != "" {
+			target += "?" + q
+		}
+		http.Redirect(ctx.Response, ctx.Request, target, http.StatusMovedPermanently)
+		return
+	}
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	q := ctx.Request.URL.Query()
+	listing := DirListing{Path: "/", Sort: q.Get("sort"), Order: q.Get("order")}
+	if dir != "." {
+		listing.Path = "/" + dir + "/"
+	}
+	if listing.Sort == "" {
+		listing.Sort = "name"
+	}
+	for _, e := range entries {
+		if !cfg.ShowHidden && strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		href := (&url.URL{Path: e.Name()}).String()
+		if e.IsDir() {
+			href += "/"
+		}
+		listing.Entries = append(listing.Entries, DirEntry{
+			Name: e.Name(), URL: href, IsDir: e.IsDir(), Size: info.Size(), ModTime: info.ModTime(),
+		})
+	}
+	sortEntries(listing.Entries, listing.Sort, listing.Order == "desc")
+
+	tmpl := cfg.ListTemplate
+	if tmpl == nil {
+		tmpl = dirListPage
+	}
+	ctx.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	ctx.Response.Header().Set("Cache-Control", "no-cache")
+	if err := tmpl.Execute(ctx.Response, listing); err != nil {
+		ctx.flow.logger().Error("flowhttp: render directory listing", "path", listing.Path, "error", err)
+	}
+}
+
+// sortEntries orders directories first, then by key.
+func sortEntries(entries []DirEntry, key string, desc bool) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		if desc {
+			a, b = b, a
+		}
+		switch key {
+		case "size":
+			if a.Size != b.Size {
+				return a.Size < b.Size
+			}
+		case "time":
+			if !a.ModTime.Equal(b.ModTime) {
+				return a.ModTime.Before(b.ModTime)
+			}
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+const fileContent = "hello, resumable world"
+
+var fileModTime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// fileFlow serves fileContent at /file via ctx.File and at
+// /static/data.txt via Static.
+func fileFlow(t *testing.T) *Flow {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(name, []byte(fileContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(name, fileModTime, fileModTime); err != nil {
+		t.Fatal(err)
+	}
+	f := NewFlow()
+	f.Stream("GET", "/file", nil, func(ctx *FlowContext) { ctx.File(name) })
+	f.Static("/static", fstest.MapFS{
+		"data.txt": {Data: []byte(fileContent), ModTime: fileModTime},
+	}, StaticConfig{})
+	return f
+}
+
+func TestFileConditionalAndRange(t *testing.T) {
+	lastModified := fileModTime.Format(http.TimeFormat)
+	tests := []struct {
+		name         string
+		method       string
+		header       map[string]string
+		status       int
+		body         string
+		contentRange string
+	}{
+		{name: "full", method: "GET", status: http.StatusOK, body: fileContent},
+		{name: "range", method: "GET", header: map[string]string{"Range": "bytes=0-4"},
+			status: http.StatusPartialContent, body: "hello", contentRange: "bytes 0-4/22"},
+		{name: "suffix range", method: "GET", header: map[string]string{"Range": "bytes=-5"},
+			status: http.StatusPartialContent, body: "world", contentRange: "bytes 17-21/22"},
+		{name: "unsatisfiable range", method: "GET", header: map[string]string{"Range": "bytes=100-"},
+			status: http.StatusRequestedRangeNotSatisfiable, contentRange: "bytes */22"},
+		{name: "if-range current", method: "GET", header: map[string]string{"Range": "bytes=7-15", "If-Range": lastModified},
+			status: http.StatusPartialContent, body: "resumable", contentRange: "bytes 7-15/22"},
+		{name: "if-range stale", method: "GET",
+			header: map[string]string{"Range": "bytes=7-15", "If-Range": fileModTime.Add(-time.Hour).Format(http.TimeFormat)},
+			status: http.StatusOK, body: fileContent},
+		{name: "if-modified-since current", method: "GET", header: map[string]string{"If-Modified-Since": lastModified},
+			status: http.StatusNotModified},
+		{name: "if-modified-since older", method: "GET",
+			header: map[string]string{"If-Modified-Since": fileModTime.Add(-time.Hour).Format(http.TimeFormat)},
+			status: http.StatusOK, body: fileContent},
+		{name: "head", method: "HEAD", status: http.StatusOK},
+		{name: "head range", method: "HEAD", header: map[string]string{"Range": "bytes=0-4"},
+			status: http.StatusPartialContent, contentRange: "bytes 0-4/22"},
+	}
+
+	f := fileFlow(t)
+	for _, target := range []string{"/file", "/static/data.txt"} {
+		for _, tt := range tests {
+			t.Run(target+"/"+tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(tt.method, target, nil)
+				for k, v := range tt.header {
+					req.Header.Set(k, v)
+				}
+				rec := f.Test(req)
+				if rec.Code != tt.status {
+					t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+				}
+				// a 416 carries an error text; only its Content-Range matters
+				if got := rec.Body.String(); tt.status != http.StatusRequestedRangeNotSatisfiable && got != tt.body {
+					t.Errorf("body = %q, want %q", got, tt.body)
+				}
+				if got := rec.Header().Get("Content-Range"); got != tt.contentRange {
+					t.Errorf("Content-Range = %q, want %q", got, tt.contentRange)
+				}
+				if tt.status == http.StatusOK || tt.status == http.StatusPartialContent {
+					if got := rec.Header().Get("Last-Modified"); got != lastModified {
+						t.Errorf("Last-Modified = %q, want %q", got, lastModified)
+					}
+					if got := rec.Header().Get("Accept-Ranges"); got != "bytes" {
+						t.Errorf("Accept-Ranges = %q, want bytes", got)
+					}
+				}
+				if tt.method == "HEAD" && tt.status == http.StatusOK {
+					if got := rec.Header().Get("Content-Length"); got != "22" {
+						t.Errorf("Content-Length = %q, want 22", got)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFileMissing(t *testing.T) {
+	f := NewFlow()
+	f.Stream("GET", "/file", nil, func(ctx *FlowContext) {
+		ctx.File(filepath.Join(t.TempDir(), "missing.txt"))
+	})
+	if rec := f.Test(httptest.NewRequest("GET", "/file", nil)); rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestHeadRoutesToGet(t *testing.T) {
+	f := NewFlow()
+	f.Stream("GET", "/get", nil, func(ctx *FlowContext) { ctx.Response.Write([]byte("body")) })
+	f.Stream("GET", "/users/:id", nil, func(ctx *FlowContext) { ctx.Response.Write([]byte(ctx.Param("id"))) })
+	f.Stream("POST", "/post", nil, func(ctx *FlowContext) {})
+
+	tests := []struct {
+		method, target string
+		status         int
+	}{
+		{"HEAD", "/get", http.StatusOK},
+		{"HEAD", "/users/42", http.StatusOK},
+		{"HEAD", "/post", http.StatusNotFound},
+		{"PUT", "/get", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rec := f.Test(httptest.NewRequest(tt.method, tt.target, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.target, rec.Code, tt.status)
+		}
+	}
+
+	if r, _ := f.Match("HEAD", "/get"); r == nil || r.Method != "GET" {
+		t.Errorf("Match(HEAD, /get) = %v, want the GET route", r)
+	}
+}
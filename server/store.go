@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store is a byte-oriented key/value store with expiry. Sessions and other
+// stateful Steps persist through it, so one backend serves all of them.
+type Store interface {
+	// Get returns the value for key, or nil (and no error) if it is missing or expired.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key; ttl <= 0 means no expiry.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetNX stores value under key only if key is missing or expired, and
+	// reports whether it did. It is atomic: of concurrent callers, on any
+	// instance sharing the backend, exactly one wins.
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// MemoryStore is an in-process Store. It is the default for single-instance setups.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	writes  int
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry)}
+}
+
+func (m *MemoryStore) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		delete(m.entries, key)
+		return nil, nil
+	}
+	return e.value, nil
+}
+
+func (m *MemoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(key, value, ttl)
+	return nil
+}
+
+func (m *MemoryStore) SetNX(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[key]; ok && (e.expires.IsZero() || time.Now().Before(e.expires)) {
+		return false, nil
+	}
+	m.set(key, value, ttl)
+	return true, nil
+}
+
+// set stores an entry; m.mu must be held.
+func (m *MemoryStore) set(key string, value []byte, ttl time.Duration) {
+	e := memoryEntry{value: value}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	m.entries[key] = e
+
+	// sweep expired entries every so often so abandoned keys don't pile up
+	m.writes++
+	if m.writes%1024 == 0 {
+		now := time.Now()
+		for k, v := range m.entries {
+			if !v.expires.IsZero() && now.After(v.expires) {
+				delete(m.entries, k)
+			}
+		}
+	}
+}
+
+func (m *MemoryStore) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisConfig configures a RedisStore.
+type RedisConfig struct {
+	// Addr is host:port (defaults to "localhost:6379").
+	Addr     string
+	Password string
+	DB       int
+	// KeyPrefix is prepended to every key, e.g. "myapp:".
+	KeyPrefix string
+	// PoolSize caps idle connections kept for reuse (defaults to 10).
+	PoolSize int
+	// Timeout bounds dialing and each command when ctx has no deadline (defaults to 3s).
+	Timeout time.Duration
+}
+
+// RedisStore is a Store backed by Redis, speaking RESP directly so no client
+// dependency is required. Expiry uses Redis' native PX option.
+type RedisStore struct {
+	cfg  RedisConfig
+	pool chan *redisConn
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// NewRedisStore creates a RedisStore. Connections are dialed lazily.
+func NewRedisStore(cfg RedisConfig) *RedisStore {
+	if cfg.Addr == "" {
+		cfg.Addr = "localhost:6379"
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 10
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 3 * time.Second
+	}
+	return &RedisStore{cfg: cfg, pool: make(chan *redisConn, cfg.PoolSize)}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := s.do(ctx, "GET", s.cfg.KeyPrefix+key)
+	if err != nil {
+		return nil, err
+	}
+	b, _ := reply.([]byte)
+	return b, nil
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", s.cfg.KeyPrefix + key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := s.do(ctx, args...)
+	return err
+}
+
+func (s *RedisStore) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	args := []string{"SET", s.cfg.KeyPrefix + key, string(value), "NX"}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	reply, err := s.do(ctx, args...)
+	if err != nil {
+		return false, err
+	}
+	return reply == "OK", nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, "DEL", s.cfg.KeyPrefix+key)
+	return err
+}
+
+// Close closes all idle connections.
+func (s *RedisStore) Close() error {
+	for {
+		select {
+		case c := <-s.pool:
+			c.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do runs one command on a pooled connection. Connections that hit an I/O
+// error are discarded; Redis error replies leave the connection usable. A
+// pooled connection the server closed while idle (idle timeout, restart)
+// fails on first use, so such failures are retried once on a fresh one.
+func (s *RedisStore) do(ctx context.Context, args ...string) (any, error) {
+	for reuse := true; ; reuse = false {
+		c, pooled, err := s.conn(ctx, reuse)
+		if err != nil {
+			return nil, err
+		}
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			deadline = time.Now().Add(s.cfg.Timeout)
+		}
+		c.SetDeadline(deadline)
+
+		reply, err := c.roundTrip(args)
+		var rerr redisError
+		if err != nil && !errors.As(err, &rerr) {
+			c.Close()
+			if pooled && ctx.Err() == nil {
+				continue
+			}
+			return nil, err
+		}
+		select {
+		case s.pool <- c:
+		default:
+			c.Close()
+		}
+		return reply, err
+	}
+}
+
+// conn returns an idle pooled connection if reuse is set and one is
+// available, reporting whether it was pooled, or dials a new one.
+func (s *RedisStore) conn(ctx context.Context, reuse bool) (*redisConn, bool, error) {
+	if reuse {
+		select {
+		case c := <-s.pool:
+			return c, true, nil
+		default:
+		}
+	}
+	d := net.Dialer{Timeout: s.cfg.Timeout}
+	nc, err := d.DialContext(ctx, "tcp", s.cfg.Addr)
+	if err != nil {
+		return nil, false, err
+	}
+	c := &redisConn{Conn: nc, r: bufio.NewReader(nc)}
+	c.SetDeadline(time.Now().Add(s.cfg.Timeout))
+	if s.cfg.Password != "" {
+		if _, err := c.roundTrip([]string{"AUTH", s.cfg.Password}); err != nil {
+			c.Close()
+			return nil, false, err
+		}
+	}
+	if s.cfg.DB != 0 {
+		if _, err := c.roundTrip([]string{"SELECT", strconv.Itoa(s.cfg.DB)}); err != nil {
+			c.Close()
+			return nil, false, err
+		}
+	}
+	return c, false, nil
+}
+
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+func (c *redisConn) roundTrip(args []string) (any, error) {
+	buf := fmt.Appendf(nil, "*%d\r\n", len(args))
+	for _, a := range args {
+		buf = fmt.Appendf(buf, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := c.Write(buf); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// readReply parses one RESP reply. Bulk strings become []byte (nil for null),
+// integers int64, simple strings string, arrays []any.
+func (c *redisConn) readReply() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, errors.New("redis: malformed reply")
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply type %q", kind)
+}
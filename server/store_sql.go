@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SQLStore is a Store backed by database/sql. It expects a table like:
+//
+//	CREATE TABLE flowhttp_store (
+//	    id         VARCHAR(255) PRIMARY KEY,
+//	    value      BLOB,   -- BYTEA on PostgreSQL
+//	    expires_at BIGINT  -- unix millis, 0 = never
+//	);
+//
+// Expired rows are ignored on read; call Cleanup periodically to delete them.
+type SQLStore struct {
+	DB *sql.DB
+	// Table defaults to "flowhttp_store".
+	Table string
+	// Numbered switches placeholders from "?" to "$1" style (PostgreSQL).
+	Numbered bool
+	// MySQL makes Set upsert with ON DUPLICATE KEY UPDATE instead of the
+	// ON CONFLICT form that PostgreSQL and SQLite use.
+	MySQL bool
+}
+
+// NewSQLStore creates a SQLStore using "?" placeholders.
+func NewSQLStore(db *sql.DB, table string) *SQLStore {
+	return &SQLStore{DB: db, Table: table}
+}
+
+func (s *SQLStore) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	var expires int64
+	row := s.DB.QueryRowContext(ctx, s.query("SELECT value, expires_at FROM %s WHERE id = %s", 1), key)
+	if err := row.Scan(&value, &expires); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if expires != 0 && time.Now().UnixMilli() > expires {
+		return nil, nil
+	}
+	return value, nil
+}
+
+// Set inserts or replaces the row with a single upsert, so concurrent Sets
+// of one key don't collide on the primary key.
+func (s *SQLStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var expires int64
+	if ttl > 0 {
+		expires = time.Now().Add(ttl).UnixMilli()
+	}
+	upsert := "INSERT INTO %s (id, value, expires_at) VALUES (%s, %s, %s) " +
+		"ON CONFLICT (id) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at"
+	if s.MySQL {
+		upsert = "INSERT INTO %s (id, value, expires_at) VALUES (%s, %s, %s) " +
+			"ON DUPLICATE KEY UPDATE value = VALUES(value), expires_at = VALUES(expires_at)"
+	}
+	_, err := s.DB.ExecContext(ctx, s.query(upsert, 3), key, value, expires)
+	return err
+}
+
+// SetNX first drops an expired row for key, then inserts, letting the
+// primary key decide between concurrent callers.
+func (s *SQLStore) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	var expires int64
+	if ttl > 0 {
+		expires = now.Add(ttl).UnixMilli()
+	}
+	_, err := s.DB.ExecContext(ctx, s.query("DELETE FROM %s WHERE id = %s AND expires_at <> 0 AND expires_at < %s", 2), key, now.UnixMilli())
+	if err != nil {
+		return false, err
+	}
+	insert := "INSERT INTO %s (id, value, expires_at) VALUES (%s, %s, %s) ON CONFLICT (id) DO NOTHING"
+	if s.MySQL {
+		insert = "INSERT IGNORE INTO %s (id, value, expires_at) VALUES (%s, %s, %s)"
+	}
+	res, err := s.DB.ExecContext(ctx, s.query(insert, 3), key, value, expires)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (s *SQLStore) Delete(ctx context.Context, key string) error {
+	_, err := s.DB.ExecContext(ctx, s.query("DELETE FROM %s WHERE id = %s", 1), key)
+	return err
+}
+
+// Cleanup deletes expired rows.
+func (s *SQLStore) Cleanup(ctx context.Context) error {
+	_, err := s.DB.ExecContext(ctx, s.query("DELETE FROM %s WHERE expires_at <> 0 AND expires_at < %s", 1), time.Now().UnixMilli())
+	return err
+}
+
+// query fills in the table name and n placeholders.
+func (s *SQLStore) query(format string, n int) string {
+	table := s.Table
+	if table == "" {
+		table = "flowhttp_store"
+	}
+	args := []any{table}
+	for i := 1; i <= n; i++ {
+		if s.Numbered {
+			args = append(args, fmt.Sprintf("$%d", i))
+		} else {
+			args = append(args, "?")
+		}
+	}
+	return fmt.Sprintf(format, args...)
+}
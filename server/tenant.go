@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TenantKey is the ctx key under which the Tenants Step stores the tenant.
+const TenantKey = "tenant"
+
+// Tenant is the resolved tenant of a request.
+type Tenant struct {
+	ID   string
+	Name string
+	Meta map[string]any
+}
+
+// TenantResolver looks a tenant ID up, e.g. in a database. It returns a nil
+// Tenant for unknown IDs; errors are treated as server errors.
+type TenantResolver interface {
+	ResolveTenant(ctx context.Context, id string) (*Tenant, error)
+}
+
+// TenantResolverFunc adapts a function to TenantResolver.
+type TenantResolverFunc func(ctx context.Context, id string) (*Tenant, error)
+
+func (fn TenantResolverFunc) ResolveTenant(ctx context.Context, id string) (*Tenant, error) {
+	return fn(ctx, id)
+}
+
+// TenantConfig configures Tenants. The ID is taken from the first source
+// that yields one: PathParam, the subdomain of BaseDomain, then Header. The
+// URL thus wins over a header naming another tenant.
+type TenantConfig struct {
+	// Header names a request header carrying the tenant ID (e.g.
+	// "X-Tenant-ID"). Clients can send any value, so only use it behind a
+	// proxy that authenticates the caller and sets or strips the header.
+	Header string
+	// PathParam names a route param carrying it, e.g. "tenant" for
+	// routes under "/t/:tenant".
+	PathParam string
+	// BaseDomain enables subdomain resolution: "acme.example.com" with
+	// BaseDomain "example.com" yields "acme".
+	BaseDomain string
+	// Resolver validates and loads the tenant. Without one, any ID is
+	// accepted as Tenant{ID: id}.
+	Resolver TenantResolver
+	// Required rejects requests without a tenant ID (400) or with an
+	// unknown one (404). Otherwise they continue without a tenant.
+	Required bool
+}
+
+// Tenants returns a Step that resolves the request's tenant and stores it
+// for ctx.Tenant().
+func Tenants(cfg TenantConfig) Step {
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		id := cfg.tenantID(ctx)
+		if id == "" {
+			if cfg.Required {
+				ctx.fail(http.StatusBadRequest, "tenant required")
+				return
+			}
+			next(ctx)
+			return
+		}
+
+		tenant := &Tenant{ID: id}
+		if cfg.Resolver != nil {
+			t, err := cfg.Resolver.ResolveTenant(ctx.Request.Context(), id)
+			if err != nil {
+				ctx.Error(err)
+				return
+			}
+			tenant = t
+		}
+		if tenant == nil {
+			if cfg.Required {
+				ctx.fail(http.StatusNotFound, "unknown tenant")
+				return
+			}
+			next(ctx)
+			return
+		}
+		ctx.Set(TenantKey, tenant)
+		next(ctx)
+	})
+}
+
+func (cfg *TenantConfig) tenantID(ctx *FlowContext) string {
+	if cfg.PathParam != "" {
+		if id := ctx.Param(cfg.PathParam); id != "" {
+			return id
+		}
+	}
+	if cfg.BaseDomain != "" {
+		host := ctx.Request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.ToLower(host)
+		if sub, ok := strings.CutSuffix(host, "."+strings.ToLower(cfg.BaseDomain)); ok && !strings.Contains(sub, ".") {
+			return sub
+		}
+	}
+	if cfg.Header != "" {
+		return ctx.Request.Header.Get(cfg.Header)
+	}
+	return ""
+}
+
+// Tenant returns the tenant resolved by the Tenants Step, or nil.
+func (f *FlowContext) Tenant() *Tenant {
+	t, _ := f.Get(TenantKey).(*Tenant)
+	return t
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIVersionKey is the ctx key holding the name of the request's API version.
+const APIVersionKey = "api.version"
+
+// APIVersion is a branch prefixed with its version name, e.g. "/v1".
+type APIVersion struct {
+	*Branch
+	name       string
+	deprecated time.Time
+	sunset     time.Time
+	link       string
+}
+
+// Version returns the branch for API version name (e.g. "v1"), mounted at
+// "/"+name with steps, creating it on first use.
+func (f *Flow) Version(name string, steps ...Step) *APIVersion {
+	if v, ok := f.versions[name]; ok {
+		return v
+	}
+	v := &APIVersion{name: name}
+	versionStep := CreateStep(func(next Sink, ctx *FlowContext) {
+		ctx.Set(APIVersionKey, v.name)
+		v.headers(ctx.Response.Header())
+		next(ctx)
+	})
+	v.Branch = f.Fork("/"+name, append([]Step{versionStep}, steps...))
+	v.versionSteps = len(v.steps)
+	if f.versions == nil {
+		f.versions = make(map[string]*APIVersion)
+	}
+	f.versions[name] = v
+	return v
+}
+
+// Name returns the version name.
+func (v *APIVersion) Name() string { return v.name }
+
+// Deprecate marks the version deprecated since at: its responses carry a
+// Deprecation header, a Sunset header if sunset is set, and a
+// rel="deprecation" Link to docs if link is set. Configure it before
+// serving.
+func (v *APIVersion) Deprecate(at, sunset time.Time, link string) *APIVersion {
+	v.deprecated, v.sunset, v.link = at, sunset, link
+	return v
+}
+
+func (v *APIVersion) headers(h http.Header) {
+	if v.deprecated.IsZero() {
+		return
+	}
+	h.Set("Deprecation", "@"+strconv.FormatInt(v.deprecated.Unix(), 10))
+	if !v.sunset.IsZero() {
+		h.Set("Sunset", v.sunset.UTC().Format(http.TimeFormat))
+	}
+	if v.link != "" {
+		h.Add("Link", "<"+v.link+`>; rel="deprecation"`)
+	}
+}
+
+// Alias re-registers routes of another version, unchanged, under this one,
+// e.g. v2.Alias(v1, "GET /users", "GET /users/:id"). The copies keep their
+// own steps, those of forks below from included, skips and metadata but
+// run this version's steps instead of from's (also for routes declared on
+// a fork with ClearSteps). With no routes, every route of from not yet
+// registered here is aliased. It panics if a named route doesn't exist.
+func (v *APIVersion) Alias(from *APIVersion, routes ...string) *APIVersion {
+	f := v.flow
+	var aliases []*Route
+	if len(routes) == 0 {
+		for _, r := range f.routes {
+			rest, ok := strings.CutPrefix(r.Pattern, from.path+"/")
+			if ok && f.stream(r.Method, r.Pattern).route == r && f.stream(r.Method, v.path+"/"+rest) == nil {
+				aliases = append(aliases, r)
+			}
+		}
+	}
+	for _, spec := range routes {
+		method, path, _ := strings.Cut(spec, " ")
+		s := f.stream(method, from.path+path)
+		if s == nil {
+			panic(fmt.Errorf("alias: no route %s %s in %s", method, path, from.name))
+		}
+		aliases = append(aliases, s.route)
+	}
+	for _, r := range aliases {
+		s := f.stream(r.Method, r.Pattern)
+		route := v.Stream(r.Method, strings.TrimPrefix(r.Pattern, from.path), s.local, s.sink)
+		route.skip = append(route.skip, r.skip...)
+		route.meta = maps.Clone(r.meta)
+	}
+	return v
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookConfig configures HMACWebhook.
+type WebhookConfig struct {
+	Secret []byte
+	// Header carries the signature (e.g. "X-Signature").
+	Header string
+	// Prefix is stripped from the header value (e.g. "sha256=").
+	Prefix string
+	// Hash defaults to sha256.New.
+	Hash func() hash.Hash
+	// Base64 reads the signature as base64 instead of hex.
+	Base64 bool
+}
+
+// HMACWebhook returns a Step that rejects requests whose body doesn't match
+// the HMAC signature in cfg.Header with 401. The body stays readable by
+// BindJSON and the handler.
+func HMACWebhook(cfg WebhookConfig) Step {
+	if cfg.Hash == nil {
+		cfg.Hash = sha256.New
+	}
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		body, ok := webhookBody(ctx)
+		if !ok {
+			return
+		}
+		value, found := strings.CutPrefix(ctx.Request.Header.Get(cfg.Header), cfg.Prefix)
+		if !found || !validMAC(cfg.Hash, cfg.Secret, body, value, cfg.Base64) {
+			ctx.fail(http.StatusUnauthorized, "invalid signature")
+			return
+		}
+		next(ctx)
+	})
+}
+
+// GitHubWebhook verifies GitHub's X-Hub-Signature-256 header.
+func GitHubWebhook(secret string) Step {
+	return HMACWebhook(WebhookConfig{Secret: []byte(secret), Header: "X-Hub-Signature-256", Prefix: "sha256="})
+}
+
+// StripeWebhook verifies Stripe-Signature headers ("t=<unix>,v1=<hex>"):
+// any v1 signature of "<t>.<body>" must match and t must lie within
+// tolerance of now (defaults to 5 minutes) to stop replays.
+func StripeWebhook(secret string, tolerance time.Duration) Step {
+	if tolerance <= 0 {
+		tolerance = 5 * time.Minute
+	}
+	key := []byte(secret)
+	return CreateStep(func(next Sink, ctx *FlowContext) {
+		body, ok := webhookBody(ctx)
+		if !ok {
+			return
+		}
+		var ts string
+		var sigs []string
+		for _, part := range strings.Split(ctx.Request.Header.Get("Stripe-Signature"), ",") {
+			k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch k {
+			case "t":
+				ts = v
+			case "v1":
+				sigs = append(sigs, v)
+			}
+		}
+		unix, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil || len(sigs) == 0 {
+			ctx.fail(http.StatusUnauthorized, "invalid signature")
+			return
+		}
+		if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+			ctx.fail(http.StatusUnauthorized, "signature timestamp outside tolerance")
+			return
+		}
+		payload := append([]byte(ts+"."), body...)
+		for _, sig := range sigs {
+			if validMAC(sha256.New, key, payload, sig, false) {
+				next(ctx)
+				return
+			}
+		}
+		ctx.fail(http.StatusUnauthorized, "invalid signature")
+	})
+}
+
+// webhookBody reads the body for verification via ctx.RawBody, so later
+// readers see it too. It responds itself and returns false on failure.
+func webhookBody(ctx *FlowContext) ([]byte, bool) {
+	body, err := ctx.RawBody()
+	if err != nil {
+		ctx.Error(err)
+		return nil, false
+	}
+	return body, true
+}
+
+func validMAC(h func() hash.Hash, key, payload []byte, sig string, b64 bool) bool {
+	var got []byte
+	var err error
+	if b64 {
+		got, err = base64.StdEncoding.DecodeString(sig)
+	} else {
+		got, err = hex.DecodeString(sig)
+	}
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(h, key)
+	mac.Write(payload)
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Favicon serves /favicon.ico with week-long caching. src is either a file
+// path, read once at registration, or the icon bytes (e.g. from embed).
+// It panics if the file can't be read.
+func (f *Flow) Favicon(src any) *Route {
+	var data []byte
+	ctype := "image/x-icon"
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		b, err := os.ReadFile(v)
+		if err != nil {
+			panic(fmt.Errorf("favicon: %v", err))
+		}
+		data = b
+		if t := mime.TypeByExtension(filepath.Ext(v)); t != "" {
+			ctype = t
+		}
+	default:
+		panic(fmt.Errorf("favicon: unsupported source type %T", src))
+	}
+	return f.serveBytes("/favicon.ico", data, ctype, "public, max-age=604800")
+}
+
+// Robots serves content as /robots.txt with day-long caching, e.g.
+// "User-agent: *\nDisallow: /admin/\n".
+func (f *Flow) Robots(content string) *Route {
+	return f.serveBytes("/robots.txt", []byte(content), "text/plain; charset=utf-8", "public, max-age=86400")
+}
+
+func (f *Flow) serveBytes(path string, data []byte, ctype, cacheControl string) *Route {
+	modTime := time.Now()
+	return f.Stream("GET", path, nil, func(ctx *FlowContext) {
+		h := ctx.Response.Header()
+		h.Set("Content-Type", ctype)
+		h.Set("Cache-Control", cacheControl)
+		http.ServeContent(ctx.Response, ctx.Request, path, modTime, bytes.NewReader(data))
+	})
+}
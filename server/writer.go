@@ -0,0 +1,144 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// hookWriter calls before exactly once, right before the response header is
+// written, so Steps can still add headers/cookies after the handler ran.
+type hookWriter struct {
+	http.ResponseWriter
+	before func()
+	fired  bool
+}
+
+func (w *hookWriter) fire() {
+	if !w.fired {
+		w.fired = true
+		w.before()
+	}
+}
+
+func (w *hookWriter) WriteHeader(code int) {
+	w.fire()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *hookWriter) Write(b []byte) (int, error) {
+	w.fire()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *hookWriter) Flush() {
+	w.fire()
+	if fl, ok := w.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *hookWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// bufferWriter captures the status and body instead of sending them, so a
+// Step can inspect or replace the response. Headers go straight to the
+// underlying writer's map. Once limit (if > 0) is exceeded, or the handler
+// flushes, it degrades to pass-through streaming.
+type bufferWriter struct {
+	http.ResponseWriter
+	status      int
+	buf         bytes.Buffer
+	limit       int
+	passthrough bool
+}
+
+func (w *bufferWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.passthrough && w.limit > 0 && w.buf.Len()+len(b) > w.limit {
+		w.startPassthrough()
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+func (w *bufferWriter) Flush() {
+	if !w.passthrough {
+		w.startPassthrough()
+	}
+	if fl, ok := w.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+func (w *bufferWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// statusCode returns the captured status (200 if the handler never set one).
+func (w *bufferWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *bufferWriter) startPassthrough() {
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.statusCode())
+	w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+}
+
+// flush sends the captured response; it's a no-op after pass-through began.
+func (w *bufferWriter) flush() {
+	if w.passthrough {
+		return
+	}
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.statusCode())
+	w.ResponseWriter.Write(w.buf.Bytes())
+}
+
+// statusWriter records the status code and body size while passing
+// everything through.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *statusWriter) Flush() {
+	if fl, ok := w.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }